@@ -1,19 +1,38 @@
 package main
 
 import (
-	"github.com/joho/godotenv"
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rustamnr/cover-letter-generator/internal/config"
 	"github.com/rustamnr/cover-letter-generator/internal/logger"
 	"github.com/rustamnr/cover-letter-generator/internal/server"
 )
 
-
 func main() {
-	// Загружаем .env
-	if err := godotenv.Load(); err != nil {
-		logger.Fatalf("failed to load .env file: %v", err)
+	configPath := flag.String("config", "", "path to a YAML config file (overrides CONFIG_FILE)")
+	flag.Parse()
+
+	// Загружаем конфигурацию из файла, .env и окружения
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Fatalf("invalid configuration:\n%v", err)
 	}
+	logger.InitLogger(cfg.Log.Level)
+
+	// Останавливаем сервер по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Запуск сервера
-	srv := server.NewServer()
-	srv.Run("8080")
+	srv, err := server.NewServer(cfg)
+	if err != nil {
+		logger.Fatalf("failed to create server: %v", err)
+	}
+	if err := srv.Run(ctx); err != nil {
+		logger.Fatalf("server stopped with error: %v", err)
+	}
 }
@@ -0,0 +1,211 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+)
+
+// anthropicVersion — версия Messages API, с которой совместим клиент
+const anthropicVersion = "2023-06-01"
+
+// AnthropicClient работает с Anthropic Messages API (/v1/messages)
+type AnthropicClient struct {
+	apiURL  string
+	apiKey  string
+	model   string
+	timeout time.Duration
+	client  *resty.Client
+}
+
+// NewAnthropicClient создает клиента Anthropic
+func NewAnthropicClient(cfg config.LLMProviderConfig) *AnthropicClient {
+	return &AnthropicClient{
+		apiURL:  cfg.APIURL,
+		apiKey:  cfg.APIKey,
+		model:   cfg.Model,
+		timeout: cfg.Timeout,
+		client:  resty.New(),
+	}
+}
+
+// anthropicRequest — тело запроса /v1/messages. Системный промпт передается
+// отдельным полем, а не сообщением.
+type anthropicRequest struct {
+	Model     string        `json:"model"`
+	System    string        `json:"system,omitempty"`
+	Messages  []ChatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+	Stream    bool          `json:"stream,omitempty"`
+}
+
+// anthropicResponse — ответ /v1/messages
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+// anthropicUsage — расход токенов в ответе и событиях потока. input_tokens
+// не включает токены, прочитанные из кэша и записанные в него.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// promptTokens считает промпт целиком, как это делают OpenAI-совместимые API
+func (u anthropicUsage) promptTokens() int {
+	return u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+}
+
+// anthropicStreamEvent — событие потока /v1/messages: message_start,
+// content_block_delta, message_delta, message_stop, ping или error
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	llmErrorResponse
+}
+
+// newRequest собирает тело запроса. Messages API требует, чтобы диалог
+// начинался с пользователя, поэтому предыдущий ответ модели добавляется к
+// системному промпту.
+func (a *AnthropicClient) newRequest(req LLMRequest, stream bool) anthropicRequest {
+	system := req.System
+	if req.Assistant != "" {
+		system += "\n\nПредыдущий ответ ассистента:\n" + req.Assistant
+	}
+	return anthropicRequest{
+		Model:     a.model,
+		System:    system,
+		Messages:  []ChatMessage{{Role: "user", Content: req.Content}},
+		MaxTokens: req.MaxTokens,
+		Stream:    stream,
+	}
+}
+
+func (a *AnthropicClient) request(ctx context.Context) *resty.Request {
+	return a.client.R().
+		SetContext(ctx).
+		SetHeader("x-api-key", a.apiKey).
+		SetHeader("anthropic-version", anthropicVersion).
+		SetHeader("Content-Type", "application/json")
+}
+
+// SendPromt отправляет промпт и возвращает текстовые блоки ответа
+func (a *AnthropicClient) SendPromt(ctx context.Context, req LLMRequest) (string, error) {
+	ctx, cancel := llmTimeout(ctx, a.timeout)
+	defer cancel()
+
+	resp, err := a.request(ctx).
+		SetBody(a.newRequest(req, false)).
+		Post(a.apiURL)
+	if err != nil {
+		return "", newTransportError(ServiceAnthropic, err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return "", newLLMError(ServiceAnthropic, resp)
+	}
+
+	var response anthropicResponse
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return "", fmt.Errorf("failed to parse anthropic response: %w", err)
+	}
+	recordUsage(ctx, &LLMUsage{
+		Service:          ServiceAnthropic,
+		Model:            a.model,
+		PromptTokens:     response.Usage.promptTokens(),
+		CompletionTokens: response.Usage.OutputTokens,
+		CacheHitTokens:   response.Usage.CacheReadInputTokens,
+	})
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", errEmptyCompletion(ServiceAnthropic)
+	}
+
+	return text.String(), nil
+}
+
+// StreamPromt отправляет промпт с "stream": true и передает onDelta текст по
+// мере генерации. Поток без message_stop считается оборванным.
+func (a *AnthropicClient) StreamPromt(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMUsage, error) {
+	ctx, cancel := llmTimeout(ctx, a.timeout)
+	defer cancel()
+
+	resp, err := a.request(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Accept", "text/event-stream").
+		SetBody(a.newRequest(req, true)).
+		Post(a.apiURL)
+	if err != nil {
+		return nil, newTransportError(ServiceAnthropic, err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	if resp.StatusCode() != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(body, maxSSELine))
+		return nil, llmErrorFromBody(ServiceAnthropic, resp.StatusCode(), data)
+	}
+
+	var (
+		usage    = LLMUsage{Service: ServiceAnthropic, Model: a.model}
+		finished bool
+	)
+	err = readSSE(ServiceAnthropic, body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to parse anthropic stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.promptTokens()
+			usage.CacheHitTokens = event.Message.Usage.CacheReadInputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				return onDelta(event.Delta.Text)
+			}
+		case "message_delta":
+			usage.CompletionTokens = event.Usage.OutputTokens
+		case "message_stop":
+			finished = true
+			return errStreamDone
+		case "error":
+			return event.toError(ServiceAnthropic)
+		}
+		return nil
+	})
+	recordUsage(ctx, &usage)
+	if err != nil {
+		return &usage, err
+	}
+	if !finished {
+		return &usage, errStreamInterrupted(ServiceAnthropic, io.ErrUnexpectedEOF)
+	}
+	return &usage, nil
+}
@@ -0,0 +1,263 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+)
+
+// ChatClient работает с OpenAI-совместимым API /chat/completions: OpenAI,
+// DeepSeek и локальными серверами вроде Ollama и llama.cpp
+type ChatClient struct {
+	service       string
+	apiURL        string
+	apiKey        string
+	model         string
+	timeout       time.Duration
+	contextWindow int  // Размер контекста модели в токенах, 0 — не ограничивать
+	streamUsage   bool // Просить usage последним чанком потока
+	client        *resty.Client
+}
+
+// NewChatClient создает клиента OpenAI-совместимого API. service попадает в
+// ошибки; без apiKey заголовок Authorization не отправляется. Usage потока
+// запрашивается через stream_options, которые понимают OpenAI и DeepSeek.
+func NewChatClient(service string, cfg config.LLMProviderConfig) *ChatClient {
+	return &ChatClient{
+		service:     service,
+		apiURL:      cfg.APIURL,
+		apiKey:      cfg.APIKey,
+		model:       cfg.Model,
+		timeout:     cfg.Timeout,
+		streamUsage: true,
+		client:      resty.New(),
+	}
+}
+
+// NewDeepSeekClient создает клиента DeepSeek
+func NewDeepSeekClient(cfg config.LLMProviderConfig) *ChatClient {
+	return NewChatClient(ServiceDeepSeek, cfg)
+}
+
+// NewOpenAIClient создает клиента OpenAI
+func NewOpenAIClient(cfg config.LLMProviderConfig) *ChatClient {
+	return NewChatClient(ServiceOpenAI, cfg)
+}
+
+// ChatMessage — сообщение диалога /chat/completions
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatCompletionRequest — тело запроса /chat/completions. Передаются только
+// параметры, которые понимают все совместимые серверы.
+type ChatCompletionRequest struct {
+	Model         string             `json:"model"`
+	Messages      []ChatMessage      `json:"messages"`
+	MaxTokens     int                `json:"max_tokens,omitempty"`
+	Temperature   float64            `json:"temperature"`
+	Stream        bool               `json:"stream"`
+	StreamOptions *ChatStreamOptions `json:"stream_options,omitempty"`
+}
+
+// ChatStreamOptions просит прислать usage последним чанком потока
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatCompletionResponse — ответ /chat/completions
+type ChatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *ChatUsage `json:"usage"`
+}
+
+// ChatUsage — блок usage ответа /chat/completions. Попадания в кэш DeepSeek
+// сообщает полем prompt_cache_hit_tokens, OpenAI — prompt_tokens_details.
+type ChatUsage struct {
+	PromptTokens         int `json:"prompt_tokens"`
+	CompletionTokens     int `json:"completion_tokens"`
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
+	PromptTokensDetails  struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (u *ChatUsage) toUsage(service, model string) *LLMUsage {
+	if u == nil {
+		return nil
+	}
+	return &LLMUsage{
+		Service:          service,
+		Model:            model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CacheHitTokens:   max(u.PromptCacheHitTokens, u.PromptTokensDetails.CachedTokens),
+	}
+}
+
+// ChatCompletionChunk — событие потока /chat/completions. Последний чанк
+// может нести только usage, а ошибка посреди потока приходит полем error.
+type ChatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *ChatUsage `json:"usage"`
+	llmErrorResponse
+}
+
+// chatMessages переводит LLMRequest в сообщения диалога, пропуская пустые
+func chatMessages(req LLMRequest) []ChatMessage {
+	var messages []ChatMessage
+	if req.System != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: req.System})
+	}
+	if req.Assistant != "" {
+		messages = append(messages, ChatMessage{Role: "assistant", Content: req.Assistant})
+	}
+	return append(messages, ChatMessage{Role: "user", Content: req.Content})
+}
+
+// SendPromt отправляет промпт и возвращает текст первого варианта ответа
+func (d *ChatClient) SendPromt(ctx context.Context, req LLMRequest) (string, error) {
+	ctx, cancel := llmTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := fitContext(d.service, req, d.contextWindow)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := d.request(ctx).
+		SetBody(ChatCompletionRequest{
+			Model:       d.model,
+			Messages:    chatMessages(req),
+			MaxTokens:   req.MaxTokens,
+			Temperature: 1,
+		}).
+		Post(d.apiURL)
+	if err != nil {
+		return "", newTransportError(d.service, err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return "", newLLMError(d.service, resp)
+	}
+
+	// Разбираем JSON-ответ
+	var response ChatCompletionResponse
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return "", fmt.Errorf("failed to parse %s response: %w", d.service, err)
+	}
+	recordUsage(ctx, response.Usage.toUsage(d.service, d.model))
+
+	// Извлекаем текст ответа
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return "", errEmptyCompletion(d.service)
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// StreamPromt отправляет промпт с "stream": true и передает onDelta текст по
+// мере генерации. Поток без [DONE] и без finish_reason считается оборванным.
+func (d *ChatClient) StreamPromt(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMUsage, error) {
+	ctx, cancel := llmTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := fitContext(d.service, req, d.contextWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ChatCompletionRequest{
+		Model:       d.model,
+		Messages:    chatMessages(req),
+		MaxTokens:   req.MaxTokens,
+		Temperature: 1,
+		Stream:      true,
+	}
+	if d.streamUsage {
+		request.StreamOptions = &ChatStreamOptions{IncludeUsage: true}
+	}
+
+	resp, err := d.request(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Accept", "text/event-stream").
+		SetBody(request).
+		Post(d.apiURL)
+	if err != nil {
+		return nil, newTransportError(d.service, err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	if resp.StatusCode() != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(body, maxSSELine))
+		return nil, llmErrorFromBody(d.service, resp.StatusCode(), data)
+	}
+
+	var (
+		usage    *LLMUsage
+		finished bool
+	)
+	err = readSSE(d.service, body, func(_, data string) error {
+		if data == "[DONE]" {
+			finished = true
+			return errStreamDone
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse %s stream chunk: %w", d.service, err)
+		}
+		if chunk.Error.Message != "" || chunk.Error.Type != "" {
+			return chunk.toError(d.service)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage(d.service, d.model)
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if chunk.Choices[0].FinishReason != nil {
+			finished = true
+		}
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			return onDelta(delta)
+		}
+		return nil
+	})
+	recordUsage(ctx, usage)
+	if err != nil {
+		return usage, err
+	}
+	if !finished {
+		return usage, errStreamInterrupted(d.service, io.ErrUnexpectedEOF)
+	}
+	return usage, nil
+}
+
+// request готовит запрос с заголовками авторизации
+func (d *ChatClient) request(ctx context.Context) *resty.Request {
+	request := d.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json")
+	if d.apiKey != "" {
+		request.SetHeader("Authorization", "Bearer "+d.apiKey)
+	}
+	return request
+}
@@ -0,0 +1,184 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+)
+
+// streamChunks answers with the given SSE lines, flushing after each event
+func streamChunks(lines ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, line := range lines {
+			fmt.Fprint(w, line+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func collectStream(client LLMStreamClient) (string, *LLMUsage, error) {
+	var text strings.Builder
+	usage, err := client.StreamPromt(context.Background(), LLMRequest{Content: "resume", MaxTokens: 100}, func(delta string) error {
+		text.WriteString(delta)
+		return nil
+	})
+	return text.String(), usage, err
+}
+
+func TestChatClientStreams(t *testing.T) {
+	fake, server := newFakeChatServer(t, streamChunks(
+		": keep-alive",
+		`data: {"choices": [{"index": 0, "delta": {"role": "assistant", "content": ""}}]}`,
+		`data: {"choices": [{"index": 0, "delta": {"content": "Здрав"}}]}`,
+		`data: {"choices": [{"index": 0, "delta": {"content": "ствуйте"}, "finish_reason": "stop"}]}`,
+		`data: {"choices": [], "usage": {"prompt_tokens": 10, "completion_tokens": 5}}`,
+		`data: [DONE]`,
+	))
+	client := NewDeepSeekClient(config.LLMProviderConfig{APIURL: server.URL, APIKey: "key", Model: "deepseek-chat"})
+
+	text, usage, err := collectStream(client)
+	if err != nil {
+		t.Fatalf("StreamPromt: %v", err)
+	}
+	if text != "Здравствуйте" {
+		t.Errorf("text = %q", text)
+	}
+	if usage == nil || usage.PromptTokens != 10 || usage.CompletionTokens != 5 {
+		t.Errorf("usage = %+v, want the final usage chunk", usage)
+	}
+	if _, _, request := fake.last(); !request.Stream || request.StreamOptions == nil || !request.StreamOptions.IncludeUsage {
+		t.Errorf("request = %+v, want stream with include_usage", request)
+	}
+}
+
+func TestLocalLLMClientAsksForStreamUsageOnlyWhenEnabled(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		fake, server := newFakeChatServer(t, streamChunks(
+			`data: {"choices": [{"delta": {"content": "Письмо"}, "finish_reason": "stop"}]}`,
+			`data: [DONE]`,
+		))
+		client := NewLocalLLMClient(config.LocalLLMConfig{
+			LLMProviderConfig: config.LLMProviderConfig{APIURL: server.URL, Model: "llama3"},
+			StreamUsage:       enabled,
+		})
+
+		if text, _, err := collectStream(client); err != nil || text != "Письмо" {
+			t.Fatalf("StreamPromt = %q, %v", text, err)
+		}
+		if _, _, request := fake.last(); (request.StreamOptions != nil) != enabled {
+			t.Errorf("stream usage %v: stream_options = %+v", enabled, request.StreamOptions)
+		}
+	}
+}
+
+func TestChatClientStreamFailures(t *testing.T) {
+	_, midStream := newFakeChatServer(t, streamChunks(
+		`data: {"choices": [{"delta": {"content": "Здрав"}}]}`,
+		`data: {"error": {"message": "Server overloaded", "type": "server_error"}}`,
+	))
+	text, _, err := collectStream(NewDeepSeekClient(config.LLMProviderConfig{APIURL: midStream.URL}))
+	if e, ok := AsError(err); !ok || e.Kind != KindUpstreamUnavailable || e.Message != "Server overloaded" {
+		t.Errorf("error in the middle of the stream: got %v", err)
+	}
+	if text != "Здрав" {
+		t.Errorf("text before the error = %q", text)
+	}
+
+	_, cut := newFakeChatServer(t, streamChunks(`data: {"choices": [{"delta": {"content": "Здрав"}}]}`))
+	if _, _, err = collectStream(NewDeepSeekClient(config.LLMProviderConfig{APIURL: cut.URL})); !errors.Is(err, &Error{Kind: KindUpstreamUnavailable, Code: "stream_interrupted"}) {
+		t.Errorf("stream without [DONE]: got %v", err)
+	}
+
+	_, limited := newFakeChatServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests"}}`))
+	})
+	if _, _, err = collectStream(NewDeepSeekClient(config.LLMProviderConfig{APIURL: limited.URL})); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("429 before the stream: got %v", err)
+	}
+
+	// The consumer stops the stream, e.g. because the browser went away
+	_, endless := newFakeChatServer(t, func(w http.ResponseWriter, r *http.Request) {
+		for {
+			fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"x\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+	stop := errors.New("client gone")
+	_, err = NewDeepSeekClient(config.LLMProviderConfig{APIURL: endless.URL}).StreamPromt(context.Background(), LLMRequest{Content: "x"},
+		func(string) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("consumer error: got %v", err)
+	}
+}
+
+func TestAnthropicClientStreams(t *testing.T) {
+	_, server := newFakeChatServer(t, streamChunks(
+		"event: message_start\ndata: {\"type\": \"message_start\", \"message\": {\"usage\": {\"input_tokens\": 12, \"output_tokens\": 1}}}",
+		"event: content_block_start\ndata: {\"type\": \"content_block_start\", \"index\": 0, \"content_block\": {\"type\": \"text\", \"text\": \"\"}}",
+		"event: ping\ndata: {\"type\": \"ping\"}",
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"index\": 0, \"delta\": {\"type\": \"text_delta\", \"text\": \"Добрый \"}}",
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"index\": 0, \"delta\": {\"type\": \"text_delta\", \"text\": \"день\"}}",
+		"event: message_delta\ndata: {\"type\": \"message_delta\", \"delta\": {\"stop_reason\": \"end_turn\"}, \"usage\": {\"output_tokens\": 7}}",
+		"event: message_stop\ndata: {\"type\": \"message_stop\"}",
+	))
+	text, usage, err := collectStream(NewAnthropicClient(config.LLMProviderConfig{APIURL: server.URL, APIKey: "key", Model: "model"}))
+	if err != nil {
+		t.Fatalf("StreamPromt: %v", err)
+	}
+	if text != "Добрый день" || usage.PromptTokens != 12 || usage.CompletionTokens != 7 {
+		t.Errorf("text = %q, usage = %+v", text, usage)
+	}
+
+	_, overloaded := newFakeChatServer(t, streamChunks(
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"Добрый\"}}",
+		"event: error\ndata: {\"type\": \"error\", \"error\": {\"type\": \"overloaded_error\", \"message\": \"Overloaded\"}}",
+	))
+	_, _, err = collectStream(NewAnthropicClient(config.LLMProviderConfig{APIURL: overloaded.URL}))
+	if e, ok := AsError(err); !ok || e.Code != "overloaded_error" || e.Service != ServiceAnthropic {
+		t.Errorf("error event: got %v", err)
+	}
+}
+
+func TestLLMUsageIsRecorded(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, body)
+		}
+	}
+	_, openai := newFakeChatServer(t, respond(`{"choices": [{"message": {"content": "Hi"}}],
+		"usage": {"prompt_tokens": 100, "completion_tokens": 20, "prompt_tokens_details": {"cached_tokens": 64}}}`))
+	_, anthropic := newFakeChatServer(t, respond(`{"content": [{"type": "text", "text": "Hi"}],
+		"usage": {"input_tokens": 30, "output_tokens": 20, "cache_read_input_tokens": 60, "cache_creation_input_tokens": 10}}`))
+
+	ctx, usage := TrackUsage(context.Background())
+	for _, client := range []LLMClient{
+		NewOpenAIClient(config.LLMProviderConfig{APIURL: openai.URL, APIKey: "key", Model: "gpt-4o-mini"}),
+		NewAnthropicClient(config.LLMProviderConfig{APIURL: anthropic.URL, APIKey: "key", Model: "claude"}),
+	} {
+		if _, err := client.SendPromt(ctx, LLMRequest{Content: "resume"}); err != nil {
+			t.Fatalf("SendPromt: %v", err)
+		}
+	}
+
+	want := []LLMUsage{
+		{Service: ServiceOpenAI, Model: "gpt-4o-mini", PromptTokens: 100, CompletionTokens: 20, CacheHitTokens: 64},
+		{Service: ServiceAnthropic, Model: "claude", PromptTokens: 100, CompletionTokens: 20, CacheHitTokens: 60},
+	}
+	if got := usage(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
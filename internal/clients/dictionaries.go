@@ -0,0 +1,402 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// dictionariesRetryInterval — как часто повторять загрузку, пока справочников нет
+const dictionariesRetryInterval = time.Minute
+
+// Dictionaries хранит справочники hh.ru в памяти и в файле cacheFile.
+// Снимок обновляется раз в ttl, а при недоступности hh.ru используется
+// сохраненный на диске. Пока справочники не загружены, проверка фильтров
+// пропускается.
+type Dictionaries struct {
+	client    *HHClient
+	cacheFile string
+	ttl       time.Duration
+
+	mu       sync.RWMutex
+	snapshot *models.Dictionaries
+	names    map[string]map[string]string // справочник -> ID -> название
+}
+
+// NewDictionaries создает Dictionaries. Пустой cacheFile отключает кэш на диске.
+func NewDictionaries(client *HHClient, cacheFile string, ttl time.Duration) *Dictionaries {
+	return &Dictionaries{client: client, cacheFile: cacheFile, ttl: ttl}
+}
+
+// Load читает снимок с диска и обновляет его с hh.ru, если он устарел.
+// Ошибка возвращается, только если справочников нет ни на диске, ни на hh.ru.
+func (d *Dictionaries) Load(ctx context.Context) error {
+	cached, err := d.readCache()
+	if err != nil {
+		logger.Errorf("failed to read hh.ru dictionaries cache: %v", err)
+	}
+	if cached != nil {
+		d.set(cached)
+		if time.Since(cached.FetchedAt) < d.ttl {
+			return nil
+		}
+	}
+
+	err = d.Refresh(ctx)
+	if err != nil && cached != nil {
+		logger.Errorf("failed to refresh hh.ru dictionaries, using snapshot from %s: %v",
+			cached.FetchedAt.Format(time.RFC3339), err)
+		return nil
+	}
+	return err
+}
+
+// Refresh загружает справочники с hh.ru и сохраняет снимок на диск
+func (d *Dictionaries) Refresh(ctx context.Context) error {
+	dictionaries, err := d.client.GetDictionaries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get dictionaries: %w", err)
+	}
+	areas, err := d.client.GetAreas(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get areas: %w", err)
+	}
+	roles, err := d.client.GetProfessionalRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get professional roles: %w", err)
+	}
+
+	snapshot := &models.Dictionaries{
+		FetchedAt:         time.Now().UTC(),
+		Dictionaries:      dictionaries,
+		Areas:             areas,
+		ProfessionalRoles: roles.Categories,
+	}
+	d.set(snapshot)
+
+	if err := d.writeCache(snapshot); err != nil {
+		logger.Errorf("failed to save hh.ru dictionaries cache: %v", err)
+	}
+	return nil
+}
+
+// Run обновляет справочники раз в ttl до отмены ctx. Пока снимка нет,
+// загрузка повторяется каждую минуту.
+func (d *Dictionaries) Run(ctx context.Context) {
+	for {
+		interval := d.ttl
+		if !d.Loaded() {
+			interval = dictionariesRetryInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if err := d.Refresh(ctx); err != nil && ctx.Err() == nil {
+			logger.Errorf("failed to refresh hh.ru dictionaries: %v", err)
+		}
+	}
+}
+
+// Loaded сообщает, есть ли в памяти снимок справочников
+func (d *Dictionaries) Loaded() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.snapshot != nil
+}
+
+// Snapshot возвращает текущий снимок или nil, если справочники не загружены
+func (d *Dictionaries) Snapshot() *models.Dictionaries {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.snapshot
+}
+
+// Name возвращает название элемента справочника по ID
+func (d *Dictionaries) Name(dictionary, id string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	name, ok := d.names[dictionary][id]
+	return name, ok
+}
+
+// Validate проверяет, что все ids есть в справочнике dictionary
+func (d *Dictionaries) Validate(dictionary string, ids ...string) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.snapshot == nil || len(ids) == 0 {
+		return nil
+	}
+
+	names, ok := d.names[dictionary]
+	if !ok {
+		// hh.ru может убрать справочник; не отклоняем запросы из-за этого
+		return nil
+	}
+
+	var unknown []string
+	for _, id := range ids {
+		if _, ok := names[id]; !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		return NewError(KindValidationFailed, "unknown_"+dictionary,
+			fmt.Sprintf("unknown %s values: %s", dictionary, strings.Join(unknown, ", ")))
+	}
+	return nil
+}
+
+// ValidateSearch проверяет значения фильтров поиска вакансий по справочникам
+func (d *Dictionaries) ValidateSearch(params models.VacancySearchParams) error {
+	checks := []struct {
+		dictionary string
+		ids        []string
+	}{
+		{models.DictArea, params.Area},
+		{models.DictExperience, params.Experience},
+		{models.DictEmployment, params.Employment},
+		{models.DictSchedule, params.Schedule},
+		{models.DictProfessionalRole, params.ProfessionalRole},
+		{models.DictWorkFormat, params.WorkFormat},
+		{models.DictCurrency, nonEmpty(params.Currency)},
+		{models.DictSearchOrder, nonEmpty(params.OrderBy)},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if err := d.Validate(check.dictionary, check.ids...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	if len(errs) > 1 {
+		return &Error{Kind: KindValidationFailed, Code: "invalid_filter_values", Message: errors.Join(errs...).Error()}
+	}
+	return nil
+}
+
+// ResolveArea находит регион по названию без учета регистра. При совпадении
+// названий выбирается регион верхнего уровня: "Москва", а не деревня в области.
+func (d *Dictionaries) ResolveArea(name string) (*models.Area, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.snapshot == nil {
+		return nil, NewError(KindUpstreamUnavailable, "dictionaries_unavailable", "hh.ru dictionaries are not loaded yet")
+	}
+
+	want := normalizeName(name)
+	level := d.snapshot.Areas
+	for len(level) > 0 {
+		var next []models.AreaTree
+		for _, area := range level {
+			if normalizeName(area.Name) == want {
+				return &models.Area{ID: area.ID, Name: area.Name}, nil
+			}
+			next = append(next, area.Areas...)
+		}
+		level = next
+	}
+
+	return nil, NewError(KindNotFound, "area_not_found", fmt.Sprintf("area %q not found", name))
+}
+
+func (d *Dictionaries) set(snapshot *models.Dictionaries) {
+	names := make(map[string]map[string]string, len(snapshot.Dictionaries)+2)
+	for dictionary, items := range snapshot.Dictionaries {
+		names[dictionary] = make(map[string]string, len(items))
+		for _, item := range items {
+			names[dictionary][item.ID] = item.Name
+		}
+	}
+
+	areas := make(map[string]string)
+	var walk func([]models.AreaTree)
+	walk = func(level []models.AreaTree) {
+		for _, area := range level {
+			areas[area.ID] = area.Name
+			walk(area.Areas)
+		}
+	}
+	walk(snapshot.Areas)
+	names[models.DictArea] = areas
+
+	roles := make(map[string]string)
+	for _, category := range snapshot.ProfessionalRoles {
+		for _, role := range category.Roles {
+			roles[role.ID] = role.Name
+		}
+	}
+	names[models.DictProfessionalRole] = roles
+
+	d.mu.Lock()
+	d.snapshot = snapshot
+	d.names = names
+	d.mu.Unlock()
+}
+
+func (d *Dictionaries) readCache() (*models.Dictionaries, error) {
+	if d.cacheFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(d.cacheFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.Dictionaries
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", d.cacheFile, err)
+	}
+	return &snapshot, nil
+}
+
+// writeCache сохраняет снимок атомарно: через временный файл и переименование
+func (d *Dictionaries) writeCache(snapshot *models.Dictionaries) error {
+	if d.cacheFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(d.cacheFile)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".dictionaries-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), d.cacheFile)
+}
+
+func normalizeName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "ё", "е")
+}
+
+func nonEmpty(values ...string) []string {
+	return slices.DeleteFunc(values, func(v string) bool { return v == "" })
+}
+
+// ===== hh.ru reference data =====
+
+// GetDictionaries возвращает справочники /dictionaries. Элементы валют
+// приводятся к общему виду: их code становится ID.
+func (c *HHClient) GetDictionaries(ctx context.Context) (map[string][]models.DictionaryItem, error) {
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + constants.Dictionaries)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Body(), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+
+	dictionaries := make(map[string][]models.DictionaryItem, len(raw))
+	for name, body := range raw {
+		var items []struct {
+			ID   string `json:"id"`
+			Code string `json:"code"`
+			Name string `json:"name"`
+		}
+		// Некоторые ключи /dictionaries — не списки; они нам не нужны
+		if err := json.Unmarshal(body, &items); err != nil {
+			continue
+		}
+
+		list := make([]models.DictionaryItem, 0, len(items))
+		for _, item := range items {
+			id := item.ID
+			if id == "" {
+				id = item.Code
+			}
+			list = append(list, models.DictionaryItem{ID: id, Name: item.Name})
+		}
+		dictionaries[name] = list
+	}
+
+	return dictionaries, nil
+}
+
+// GetAreas возвращает дерево регионов /areas
+func (c *HHClient) GetAreas(ctx context.Context) ([]models.AreaTree, error) {
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + constants.Areas)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
+	}
+
+	var areas []models.AreaTree
+	if err := json.Unmarshal(resp.Body(), &areas); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+	return areas, nil
+}
+
+// GetProfessionalRoles возвращает справочник /professional_roles
+func (c *HHClient) GetProfessionalRoles(ctx context.Context) (*models.ProfessionalRolesResponse, error) {
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + constants.ProfessionalRoles)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
+	}
+
+	var roles models.ProfessionalRolesResponse
+	if err := json.Unmarshal(resp.Body(), &roles); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+	return &roles, nil
+}
@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// fakeDictionaries serves /dictionaries, /areas and /professional_roles until
+// offline is set, then answers 503
+func fakeDictionaries(t *testing.T, offline *atomic.Bool) (*fakeHHServer, *HHClient) {
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if offline.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/dictionaries":
+			w.Write([]byte(`{
+				"experience": [{"id": "between1And3", "name": "От 1 года до 3 лет"}],
+				"currency": [{"code": "RUR", "name": "Рубли"}],
+				"vacancy_search_order": [{"id": "relevance", "name": "по соответствию"}],
+				"not_a_list": {"id": "x"}
+			}`))
+		case "/areas":
+			w.Write([]byte(`[
+				{"id": "2019", "name": "Московская область", "areas": [{"id": "9999", "name": "Москва"}]},
+				{"id": "1", "name": "Москва", "areas": []}
+			]`))
+		case "/professional_roles":
+			w.Write([]byte(`{"categories": [{"id": "11", "name": "IT", "roles": [{"id": "96", "name": "Программист, разработчик"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return fake, NewHHClient(cfg, nil)
+}
+
+func TestDictionariesLookups(t *testing.T) {
+	var offline atomic.Bool
+	_, client := fakeDictionaries(t, &offline)
+	dictionaries := NewDictionaries(client, "", time.Hour)
+
+	if err := dictionaries.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if name, ok := dictionaries.Name(models.DictCurrency, "RUR"); !ok || name != "Рубли" {
+		t.Errorf("currency RUR = %q, %v", name, ok)
+	}
+	if name, ok := dictionaries.Name(models.DictProfessionalRole, "96"); !ok || name != "Программист, разработчик" {
+		t.Errorf("professional role 96 = %q, %v", name, ok)
+	}
+
+	area, err := dictionaries.ResolveArea("москва")
+	if err != nil || area.ID != "1" {
+		t.Errorf("ResolveArea(москва) = %+v, %v; want the top-level area 1", area, err)
+	}
+	if _, err := dictionaries.ResolveArea("Атлантида"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveArea(Атлантида): got %v, want ErrNotFound", err)
+	}
+
+	valid := models.VacancySearchParams{Area: []string{"9999"}, Experience: []string{"between1And3"}, Currency: "RUR", OrderBy: "relevance"}
+	if err := dictionaries.ValidateSearch(valid); err != nil {
+		t.Errorf("ValidateSearch(valid): %v", err)
+	}
+	invalid := models.VacancySearchParams{Experience: []string{"forever"}}
+	if err := dictionaries.ValidateSearch(invalid); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("ValidateSearch(invalid): got %v, want ErrValidationFailed", err)
+	}
+}
+
+func TestDictionariesWorkOfflineFromCache(t *testing.T) {
+	var offline atomic.Bool
+	fake, client := fakeDictionaries(t, &offline)
+	cacheFile := filepath.Join(t.TempDir(), "dictionaries.json")
+
+	if err := NewDictionaries(client, cacheFile, time.Hour).Load(context.Background()); err != nil {
+		t.Fatalf("initial Load: %v", err)
+	}
+	requests := len(fake.recorded())
+
+	// A fresh snapshot is used without asking hh.ru
+	fresh := NewDictionaries(client, cacheFile, time.Hour)
+	if err := fresh.Load(context.Background()); err != nil {
+		t.Fatalf("Load from fresh cache: %v", err)
+	}
+	if n := len(fake.recorded()); n != requests {
+		t.Errorf("fresh cache: sent %d requests to hh.ru", n-requests)
+	}
+
+	// A stale snapshot is still used while hh.ru is unavailable
+	offline.Store(true)
+	stale := NewDictionaries(client, cacheFile, time.Nanosecond)
+	if err := stale.Load(context.Background()); err != nil {
+		t.Fatalf("Load from stale cache while offline: %v", err)
+	}
+	if _, ok := stale.Name(models.DictExperience, "between1And3"); !ok {
+		t.Error("stale cache: experience between1And3 not found")
+	}
+
+	// Without a snapshot there is nothing to fall back to
+	if err := NewDictionaries(client, "", time.Hour).Load(context.Background()); err == nil {
+		t.Error("Load without cache while offline: expected an error")
+	}
+}
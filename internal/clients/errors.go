@@ -0,0 +1,242 @@
+package clients
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrorKind классифицирует ошибки, чтобы их можно было отобразить в HTTP-статус
+type ErrorKind string
+
+const (
+	KindNotFound            ErrorKind = "not_found"
+	KindUnauthorized        ErrorKind = "unauthorized"
+	KindForbidden           ErrorKind = "forbidden"
+	KindRateLimited         ErrorKind = "rate_limited"
+	KindValidationFailed    ErrorKind = "validation_failed"
+	KindUpstreamUnavailable ErrorKind = "upstream_unavailable"
+)
+
+// Сервисы, от которых приходят ошибки. Имена языковых моделей совпадают с
+// именами провайдеров в конфигурации и в параметре ?provider=.
+const (
+	ServiceHH        = "hh.ru"
+	ServiceDeepSeek  = "deepseek"
+	ServiceOpenAI    = "openai"
+	ServiceAnthropic = "anthropic"
+	ServiceLocal     = "local"
+)
+
+// Образцы для errors.Is: ошибка совпадает с образцом, если у них одинаковый Kind
+var (
+	ErrNotFound            = &Error{Kind: KindNotFound}
+	ErrUnauthorized        = &Error{Kind: KindUnauthorized}
+	ErrForbidden           = &Error{Kind: KindForbidden}
+	ErrRateLimited         = &Error{Kind: KindRateLimited}
+	ErrValidationFailed    = &Error{Kind: KindValidationFailed}
+	ErrUpstreamUnavailable = &Error{Kind: KindUpstreamUnavailable}
+)
+
+// Error — ошибка с классификацией и машиночитаемым кодом. Для ошибок внешних
+// API Code содержит код из ответа сервиса, например "token_expired" или
+// "already_applied".
+type Error struct {
+	Kind       ErrorKind
+	Code       string
+	Message    string
+	Service    string // Пусто для ошибок самого приложения
+	StatusCode int    // HTTP-статус ответа внешнего сервиса
+	Err        error
+}
+
+// NewError создает ошибку приложения, не связанную с внешним сервисом
+func NewError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Kind)
+	}
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	if e.Service != "" {
+		msg = e.Service + ": " + msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && (t.Code == "" || t.Code == e.Code)
+}
+
+// newTransportError оборачивает ошибку соединения с внешним сервисом.
+// Отмена контекста сохраняется, чтобы ее можно было отличить от недоступности.
+func newTransportError(service string, err error) error {
+	return &Error{
+		Kind:    KindUpstreamUnavailable,
+		Code:    "upstream_unreachable",
+		Message: "request failed",
+		Service: service,
+		Err:     err,
+	}
+}
+
+// hhErrorResponse — тело ошибки hh.ru
+type hhErrorResponse struct {
+	Description string `json:"description"`
+	BadArgument string `json:"bad_argument"`
+	Errors      []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"errors"`
+}
+
+func (r *hhErrorResponse) hasType(errType string) bool {
+	for _, e := range r.Errors {
+		if e.Type == errType {
+			return true
+		}
+	}
+	return false
+}
+
+// newHHError классифицирует неуспешный ответ hh.ru
+func newHHError(resp *resty.Response) error {
+	var body hhErrorResponse
+	_ = json.Unmarshal(resp.Body(), &body)
+
+	e := &Error{
+		Kind:       kindFromStatus(resp.StatusCode()),
+		Service:    ServiceHH,
+		StatusCode: resp.StatusCode(),
+		Message:    body.Description,
+	}
+
+	if len(body.Errors) > 0 {
+		e.Code = body.Errors[0].Value
+		if e.Code == "" {
+			e.Code = body.Errors[0].Type
+		}
+	}
+	if body.BadArgument != "" && e.Kind == KindValidationFailed {
+		e.Message = "bad argument: " + body.BadArgument
+	}
+
+	// Просроченный или отозванный токен hh.ru возвращает как 403 с ошибкой oauth
+	if resp.StatusCode() == http.StatusForbidden && body.hasType("oauth") {
+		e.Kind = KindUnauthorized
+	}
+
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode())
+	}
+	return e
+}
+
+// llmErrorResponse — тело ошибки OpenAI-совместимых API и Anthropic
+type llmErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+// newLLMError классифицирует неуспешный ответ языковой модели
+func newLLMError(service string, resp *resty.Response) error {
+	return llmErrorFromBody(service, resp.StatusCode(), resp.Body())
+}
+
+// llmErrorFromBody классифицирует ответ языковой модели по статусу и телу.
+// Ошибки авторизации и валидации здесь — проблема конфигурации приложения, а
+// не пользователя, поэтому они считаются недоступностью сервиса.
+func llmErrorFromBody(service string, status int, data []byte) error {
+	var body llmErrorResponse
+	_ = json.Unmarshal(data, &body)
+
+	e := body.toError(service)
+	e.StatusCode = status
+
+	switch status {
+	case http.StatusTooManyRequests:
+		e.Kind = KindRateLimited
+	case http.StatusPaymentRequired:
+		e.Code = "insufficient_balance"
+	}
+
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+// toError переводит тело ошибки в *Error. Используется и для ошибок, которые
+// приходят событием посреди потока, когда статус уже 200.
+func (r llmErrorResponse) toError(service string) *Error {
+	e := &Error{
+		Kind:    KindUpstreamUnavailable,
+		Service: service,
+		Message: r.Error.Message,
+		Code:    r.Error.Type,
+	}
+	if code, ok := r.Error.Code.(string); ok && code != "" {
+		e.Code = code
+	}
+	if e.Code == "rate_limit_error" || e.Code == "rate_limit_exceeded" {
+		e.Kind = KindRateLimited
+	}
+	return e
+}
+
+// errEmptyCompletion — модель ответила без текста
+func errEmptyCompletion(service string) error {
+	return &Error{
+		Kind:    KindUpstreamUnavailable,
+		Code:    "empty_completion",
+		Message: "response contains no text",
+		Service: service,
+	}
+}
+
+func kindFromStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusNotFound:
+		return KindNotFound
+	case status == http.StatusUnauthorized:
+		return KindUnauthorized
+	case status == http.StatusForbidden:
+		return KindForbidden
+	case status == http.StatusTooManyRequests:
+		return KindRateLimited
+	case status >= 500:
+		return KindUpstreamUnavailable
+	case status >= 400:
+		return KindValidationFailed
+	default:
+		return KindUpstreamUnavailable
+	}
+}
+
+// AsError извлекает *Error из цепочки ошибок
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
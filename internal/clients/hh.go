@@ -1,350 +1,913 @@
 package clients
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
+	"iter"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/microcosm-cc/bluemonday"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
 	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
 	"github.com/rustamnr/cover-letter-generator/internal/logger"
 	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
 )
 
 type HHClient struct {
+	authURL      string
 	apiURL       string
+	oauthURL     string
 	ClientID     string
 	clientSecret string
 	redirectURI  string
+	appToken     string
 	client       *resty.Client
-	accessToken  string
+	timeout      time.Duration
+	tokens       storage.TokenStore
+	refreshLocks *userLocks
+	limiter      *RateLimiter
+	names        models.NameResolver // Справочники для названий, которых нет в ответах
+
+	userID string
+	token  *models.HHToken
 }
 
-func NewHHClient() *HHClient {
+func NewHHClient(cfg config.HHConfig, tokens storage.TokenStore) *HHClient {
+	limiter := NewRateLimiter(cfg.RateLimit, cfg.RateBurst)
+
 	return &HHClient{
-		apiURL:       os.Getenv("HH_API_URL"),
-		ClientID:     os.Getenv("HH_CLIENT_ID"),
-		clientSecret: os.Getenv("HH_CLIENT_SECRET"),
-		client:       resty.New(),
+		authURL:      cfg.URL,
+		apiURL:       cfg.APIURL,
+		oauthURL:     cfg.OAuthURL,
+		ClientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURI:  cfg.RedirectURI,
+		appToken:     cfg.AppToken,
+		client:       newHHRestyClient(cfg, limiter),
+		timeout:      cfg.Timeout,
+		tokens:       tokens,
+		refreshLocks: newUserLocks(),
+		limiter:      limiter,
+	}
+}
+
+// newHHRestyClient настраивает HTTP-клиент hh.ru: таймаут попытки, повторы
+// идемпотентных запросов с экспоненциальной паузой и джиттером, учет
+// Retry-After и ограничение частоты запросов каждого пользователя.
+func newHHRestyClient(cfg config.HHConfig, limiter *RateLimiter) *resty.Client {
+	client := resty.New().
+		SetTimeout(cfg.RequestTimeout).
+		SetRetryCount(cfg.RetryCount).
+		SetRetryWaitTime(cfg.RetryWait).
+		SetRetryMaxWaitTime(cfg.RetryMaxWait).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(shouldRetry).
+		AddRetryHook(func(resp *resty.Response, err error) {
+			if resp != nil && resp.Request != nil {
+				logger.Infof("retrying hh.ru request %s %s: status %d, error %v",
+					resp.Request.Method, resp.Request.URL, resp.StatusCode(), err)
+			}
+		})
+
+	// hh.ru отклоняет запросы приложений, которые не представились
+	userAgent := hhUserAgent(cfg.AppName, cfg.AppContact)
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		r.SetHeader("HH-User-Agent", userAgent)
+		return nil
+	})
+
+	// Middleware срабатывает на каждую попытку, так что повторы тоже расходуют лимит
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		return limiter.Wait(r.Context(), limiterKey(r.Context()))
+	})
+
+	return client
+}
+
+// hhUserAgent формирует HH-User-Agent вида "MyApp/1.0 (me@example.com)"
+func hhUserAgent(name, contact string) string {
+	if contact == "" {
+		return name
+	}
+	return name + " (" + contact + ")"
+}
+
+// shouldRetry повторяет только GET-запросы: отклик или сообщение, отправленные
+// повторно после обрыва соединения, могут задвоиться
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil || resp.Request.Method != http.MethodGet {
+		return false
+	}
+	// Отмена или истечение контекста операции resty проверяет сам, так что здесь
+	// ошибка — сбой сети или таймаут отдельной попытки
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= http.StatusInternalServerError
+}
+
+// retryAfter читает паузу из заголовка Retry-After (секунды или HTTP-дата).
+// Ноль означает обычную экспоненциальную паузу.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	header := resp.Header().Get("Retry-After")
+	if header == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
 	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0), nil
+	}
+	return 0, nil
+}
+
+type limiterKeyType struct{}
+
+// withLimiterKey помечает запросы пользователя для RateLimiter
+func withLimiterKey(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, limiterKeyType{}, userID)
+}
+
+func limiterKey(ctx context.Context) string {
+	key, _ := ctx.Value(limiterKeyType{}).(string)
+	return key
 }
 
 // ===== Authentication and Token Management =====
 
-func (c *HHClient) SetAccessToken(token string) {
-	c.accessToken = token
+// hhTokenResponse is the response of https://hh.ru/oauth/token
+type hhTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func (t *hhTokenResponse) toToken() *models.HHToken {
+	token := &models.HHToken{
+		AccessToken:  t.AccessToken,
+		RefreshToken: t.RefreshToken,
+	}
+	if t.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return token
+}
+
+// WithToken returns a copy of the client that calls hh.ru on behalf of the user.
+// The copy shares the HTTP client and token store, so it is cheap to create per
+// request. userID may be empty, in which case a refreshed token is not persisted.
+func (c *HHClient) WithToken(userID string, token *models.HHToken) *HHClient {
+	userClient := *c
+	userClient.userID = userID
+	userClient.token = token
+	return &userClient
+}
+
+// WithNames возвращает копию клиента, подставляющую в вакансии названия из
+// справочников names, если hh.ru прислал только ID
+func (c *HHClient) WithNames(names models.NameResolver) *HHClient {
+	namedClient := *c
+	namedClient.names = names
+	return &namedClient
+}
+
+func (c *HHClient) GetToken() *models.HHToken {
+	return c.token
+}
+
+// AuthURL returns the hh.ru authorization URL carrying the given state
+func (c *HHClient) AuthURL(state string) string {
+	return helpers.GetAuthURL(c.authURL, c.ClientID, c.redirectURI, state)
+}
+
+func (c *HHClient) ExchangeCodeForToken(ctx context.Context, code string) (*models.HHToken, error) {
+	formData := map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     c.ClientID,
+		"client_secret": c.clientSecret,
+		"code":          code,
+	}
+	// redirect_uri must match the one sent to the authorization endpoint
+	if c.redirectURI != "" {
+		formData["redirect_uri"] = c.redirectURI
+	}
+	return c.requestToken(ctx, formData)
 }
 
-func (c *HHClient) GetAccessToken() string {
-	return c.accessToken
+// RefreshToken exchanges a refresh token for a new token pair
+func (c *HHClient) RefreshToken(ctx context.Context, refreshToken string) (*models.HHToken, error) {
+	return c.requestToken(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
 }
 
-func (c *HHClient) ExchangeCodeForToken(code string) (string, error) {
-	resp, err := c.client.R().
-		SetFormData(map[string]string{
-			"grant_type":    "authorization_code",
-			"client_id":     c.ClientID,
-			"client_secret": c.clientSecret,
-			"code":          code,
-			"redirect_uri":  c.redirectURI,
-		}).
-		Post(constants.HHOAuth)
+func (c *HHClient) requestToken(ctx context.Context, formData map[string]string) (*models.HHToken, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.R().SetContext(ctx).
+		SetFormData(formData).
+		Post(c.oauthURL)
 
 	if err != nil {
-		return "", fmt.Errorf("ошибка запроса к hh.ru: %w", err)
+		return nil, newTransportError(ServiceHH, err)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return "", fmt.Errorf("не удалось получить токен: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
-	var tokenData map[string]interface{}
+	var tokenData hhTokenResponse
 	if err := json.Unmarshal(resp.Body(), &tokenData); err != nil {
-		return "", fmt.Errorf("ошибка при разборе ответа: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+
+	if tokenData.AccessToken == "" {
+		return nil, errors.New("hh.ru token response has no access_token")
 	}
 
-	accessToken, ok := tokenData["access_token"].(string)
+	return tokenData.toToken(), nil
+}
+
+// withTimeout bounds a single client operation, including token refreshes and
+// retries, and keys its requests to the current user's rate limit
+func (c *HHClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = withLimiterKey(ctx, c.userID)
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// authorized sends a request with the user's access token. An expired token is
+// refreshed before the call, and an authorization failure is retried once after
+// a refresh. Transport errors are returned as *Error.
+func (c *HHClient) authorized(ctx context.Context, send func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	if c.token == nil {
+		return nil, NewError(KindUnauthorized, "not_authorized", "hh.ru access token is not set")
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if c.token.Expired() && c.token.CanRefresh() {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := send(c.client.R().SetContext(ctx).SetHeader("Authorization", "Bearer "+c.token.AccessToken))
+	if err != nil {
+		return nil, newTransportError(ServiceHH, err)
+	}
+	if !isAuthFailure(resp) || !c.token.CanRefresh() {
+		return resp, nil
+	}
+
+	logger.Infof("hh.ru rejected access token of user %q, refreshing", c.userID)
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err = send(c.client.R().SetContext(ctx).SetHeader("Authorization", "Bearer "+c.token.AccessToken))
+	if err != nil {
+		return nil, newTransportError(ServiceHH, err)
+	}
+	return resp, nil
+}
+
+// public sends a request to an endpoint that also accepts application-level
+// auth: with the user's token when there is one, otherwise with the app token.
+func (c *HHClient) public(ctx context.Context, send func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	if c.token != nil {
+		return c.authorized(ctx, send)
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	r := c.client.R().SetContext(ctx)
+	if c.appToken != "" {
+		r.SetHeader("Authorization", "Bearer "+c.appToken)
+	}
+
+	resp, err := send(r)
+	if err != nil {
+		return nil, newTransportError(ServiceHH, err)
+	}
+	return resp, nil
+}
+
+// defaultRefreshTimeout bounds a token refresh when the client has no timeout
+const defaultRefreshTimeout = 30 * time.Second
+
+// userLocks hands out a mutex per user, so a slow token refresh of one user
+// does not hold up refreshes of the others
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+	holders int // Goroutines holding or waiting for the lock
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{locks: make(map[string]*userLock)}
+}
+
+// lock locks the user's mutex and returns its unlock. The mutex is dropped
+// once nobody holds or waits for it.
+func (l *userLocks) lock(userID string) (unlock func()) {
+	l.mu.Lock()
+	lock, ok := l.locks[userID]
 	if !ok {
-		return "", errors.New("access_token не найден")
+		lock = &userLock{}
+		l.locks[userID] = lock
 	}
+	lock.holders++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
 
-	return accessToken, nil
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if lock.holders--; lock.holders == 0 {
+			delete(l.locks, userID)
+		}
+	}
+}
+
+// refresh obtains a new token pair and stores it for the current user
+func (c *HHClient) refresh(ctx context.Context) error {
+	unlock := c.refreshLocks.lock(c.userID)
+	defer unlock()
+
+	// A concurrent request of the same user may have refreshed the token already;
+	// hh.ru refresh tokens are single-use, so reuse the stored one.
+	if c.tokens != nil && c.userID != "" {
+		stored, err := c.tokens.Get(c.userID)
+		if err == nil && stored.AccessToken != c.token.AccessToken && !stored.Expired() {
+			c.token = stored
+			return nil
+		}
+	}
+
+	// hh.ru retires the old refresh token as soon as it issues a new pair, so the
+	// exchange and the save must finish even if the caller goes away: otherwise
+	// the new pair is lost and the user has to authorize again.
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = defaultRefreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	token, err := c.RefreshToken(ctx, c.token.RefreshToken)
+	if err != nil {
+		return &Error{
+			Kind:    KindUnauthorized,
+			Code:    "token_refresh_failed",
+			Message: "failed to refresh access token, authorize again",
+			Service: ServiceHH,
+			Err:     err,
+		}
+	}
+
+	if c.tokens != nil && c.userID != "" {
+		if err := c.tokens.Save(c.userID, token); err != nil {
+			return fmt.Errorf("failed to save refreshed token: %w", err)
+		}
+	}
+
+	c.token = token
+	return nil
+}
+
+// isAuthFailure reports whether hh.ru rejected the access token. hh.ru answers
+// 401 for a missing token and 403 with an "oauth" error for an expired one.
+func isAuthFailure(resp *resty.Response) bool {
+	switch resp.StatusCode() {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		var body hhErrorResponse
+		if err := json.Unmarshal(resp.Body(), &body); err != nil {
+			return false
+		}
+		return body.hasType("oauth")
+	}
+	return false
 }
 
 // ====== User and Resume Management =====
 
-func (c *HHClient) GetUserID(accessToken string) (string, error) {
-	resp, err := c.client.R().
+func (c *HHClient) GetUserID(ctx context.Context, accessToken string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.client.R().SetContext(ctx).
 		SetHeader("Authorization", "Bearer "+accessToken).
 		Get(c.apiURL + constants.Me)
 
 	if err != nil {
-		return "", fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return "", newTransportError(ServiceHH, err)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return "", fmt.Errorf("не удалось получить user_id: %s", resp.String())
+		return "", newHHError(resp)
 	}
 
 	var userData map[string]interface{}
 	if err := json.Unmarshal(resp.Body(), &userData); err != nil {
-		return "", fmt.Errorf("ошибка при разборе ответа: %w", err)
+		return "", fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	userID, ok := userData["id"].(string)
 	if !ok {
-		return "", errors.New("user_id не найден")
+		return "", errors.New("hh.ru response has no user id")
 	}
 
 	return userID, nil
 }
 
-func (c *HHClient) GetResume(resumeID string) (*models.Resume, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + fmt.Sprintf(constants.Resume, resumeID))
+func (c *HHClient) GetResume(ctx context.Context, resumeID string) (*models.Resume, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.Resume, resumeID))
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("не удалось получить резюме: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var resume models.Resume
 	if err := json.Unmarshal(resp.Body(), &resume); err != nil {
-		return nil, fmt.Errorf("ошибка при разборе ответа: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	return &resume, nil
 }
 
-func (c *HHClient) GetShortResume(resumeID string) (*models.ResumeShort, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + fmt.Sprintf(constants.Resume, resumeID))
+func (c *HHClient) GetShortResume(ctx context.Context, resumeID string) (*models.ResumeShort, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.Resume, resumeID))
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("не удалось получить резюме: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var resume models.ResumeShort
 	if err := json.Unmarshal(resp.Body(), &resume); err != nil {
-		return nil, fmt.Errorf("ошибка при разборе ответа: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	return &resume, nil
 }
 
-func (c *HHClient) GetResumes() (*models.ResumesResponse, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + constants.ResumesMine)
+func (c *HHClient) GetResumes(ctx context.Context) (*models.ResumesResponse, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + constants.ResumesMine)
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("не удалось получить список резюме: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var resumes models.ResumesResponse
 	if err := json.Unmarshal(resp.Body(), &resumes); err != nil {
-		return nil, fmt.Errorf("ошибка при разборе ответа: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	return &resumes, nil
 }
 
-func (c *HHClient) GetVacancyByID(vacancyID string) (*models.Vacancy, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + fmt.Sprintf(constants.Vacancy, vacancyID))
+func (c *HHClient) GetVacancyByID(ctx context.Context, vacancyID string) (*models.Vacancy, error) {
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.Vacancy, vacancyID))
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vacancy: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("unsuccessful response from hh.ru: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var vacancy models.Vacancy
 	if err := json.Unmarshal(resp.Body(), &vacancy); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	return &vacancy, nil
 }
 
-func (c *HHClient) GetShortVacancyByID(vacancyID string) (*models.VacancyShort, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + fmt.Sprintf(constants.Vacancy, vacancyID))
+func (c *HHClient) GetShortVacancyByID(ctx context.Context, vacancyID string) (*models.VacancyShort, error) {
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.Vacancy, vacancyID))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vacancy: %w", err)
+		return nil, err
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("unsuccessful response from hh.ru: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var vacancy models.VacancyShort
 	if err := json.Unmarshal(resp.Body(), &vacancy); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
 	vacancy.Description = cleanHTML(vacancy.Description)
 	if vacancy.BrandedDescription != nil {
 		*vacancy.BrandedDescription = cleanHTML(*vacancy.BrandedDescription)
 	}
+	vacancy.ResolveNames(c.names)
 
 	logger.Debugf("vacancy: %+v", vacancy)
 	return &vacancy, nil
 }
 
-func (c *HHClient) GetUserApplications() ([]models.ApplicationItem, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		Get(c.apiURL + constants.Negotiations)
+// SearchVacancies ищет вакансии через GET /vacancies. Без пользователя поиск
+// выполняется от имени приложения.
+func (c *HHClient) SearchVacancies(
+	ctx context.Context, params models.VacancySearchParams) (*models.VacanciesResponse[models.VacancyShort], error) {
+	if err := params.Validate(); err != nil {
+		return nil, &Error{Kind: KindValidationFailed, Code: "invalid_search_params", Message: err.Error(), Err: err}
+	}
 
+	resp, err := c.public(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParamsFromValues(params.Query()).
+			Get(c.apiURL + constants.Vacancies)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return nil, err
 	}
-
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("не удалось получить список заявок: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
-	var applications []models.ApplicationItem
-	if err := json.Unmarshal(resp.Body(), &applications); err != nil {
-		return nil, fmt.Errorf("ошибка при разборе ответа: %w", err)
+	var found models.VacanciesResponse[models.Vacancy]
+	if err := json.Unmarshal(resp.Body(), &found); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
-	return applications, nil
+	return toShortVacancies(&found, c.names), nil
 }
 
-func (c *HHClient) GetUserFirstFoundedApplication(accessToken string) (*models.APIApplicationsResponse, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+accessToken).
-		Get(c.apiURL + "/negotiations" + "?" + "per_page=1")
+// toShortVacancies сохраняет постраничные метаданные ответа, сокращая вакансии
+func toShortVacancies(
+	found *models.VacanciesResponse[models.Vacancy], names models.NameResolver) *models.VacanciesResponse[models.VacancyShort] {
+	short := &models.VacanciesResponse[models.VacancyShort]{
+		Found:   found.Found,
+		Items:   make([]models.VacancyShort, 0, len(found.Items)),
+		Page:    found.Page,
+		Pages:   found.Pages,
+		PerPage: found.PerPage,
+	}
+	for i := range found.Items {
+		short.Items = append(short.Items, *found.Items[i].ToShort(names))
+	}
+	return short
+}
 
-	logger.Infof("resp: %v", resp)
+// GetNegotiations возвращает страницу откликов пользователя. Если заданы
+// фильтры, которых нет у hh.ru (state, updated_from, updated_to), отклики
+// собираются со всех страниц hh.ru, но не дальше MaxSearchDepth, фильтруются
+// и делятся на страницы здесь; found и pages считаются по отфильтрованным.
+func (c *HHClient) GetNegotiations(ctx context.Context, params models.NegotiationsParams) (*models.APIApplicationsResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &Error{Kind: KindValidationFailed, Code: "invalid_negotiations_params", Message: err.Error(), Err: err}
+	}
+	if !params.FiltersLocally() {
+		return c.getNegotiationsPage(ctx, params.Query())
+	}
+
+	perPage := params.PerPage
+	if perPage == 0 {
+		perPage = models.DefaultPerPage
+	}
+
+	upstream := params
+	upstream.Page, upstream.PerPage = 0, 100
+	matched := []models.ApplicationItem{}
+	for ; (upstream.Page+1)*upstream.PerPage <= models.MaxSearchDepth; upstream.Page++ {
+		page, err := c.getNegotiationsPage(ctx, upstream.Query())
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if params.Matches(item) {
+				matched = append(matched, item)
+			}
+		}
+		if len(page.Items) == 0 || upstream.Page+1 >= page.Pages {
+			break
+		}
+	}
+
+	from := min(params.Page*perPage, len(matched))
+	to := min(from+perPage, len(matched))
+	return &models.APIApplicationsResponse{
+		Found:   len(matched),
+		Items:   matched[from:to],
+		Page:    params.Page,
+		Pages:   (len(matched) + perPage - 1) / perPage,
+		PerPage: perPage,
+	}, nil
+}
 
-	if err != nil || resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+// getNegotiationsPage запрашивает одну страницу GET /negotiations
+func (c *HHClient) getNegotiationsPage(ctx context.Context, query url.Values) (*models.APIApplicationsResponse, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParamsFromValues(query).
+			Get(c.apiURL + constants.Negotiations)
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	var applicationsResponse models.APIApplicationsResponse
-	if err := json.Unmarshal(resp.Body(), &applicationsResponse); err != nil {
-		return nil, fmt.Errorf("ошибка при разборе ответа: %w", err)
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
 	}
 
-	if len(applicationsResponse.Items) == 0 {
-		return nil, errors.New("заявки не найдены")
+	var negotiations models.APIApplicationsResponse
+	if err := json.Unmarshal(resp.Body(), &negotiations); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
-
-	return &applicationsResponse, nil
+	if negotiations.Items == nil {
+		negotiations.Items = []models.ApplicationItem{}
+	}
+	return &negotiations, nil
 }
 
+// GetSuitableVacancies возвращает страницу вакансий, подходящих к резюме.
+// Фильтры те же, что у поиска вакансий.
 func (c *HHClient) GetSuitableVacancies(
-	resumeID string, queryParams map[string]string) ([]models.Vacancy, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		SetQueryParams(queryParams). // Устанавливаем параметры запроса
-		Get(c.apiURL + fmt.Sprintf("/resumes/%s/similar_vacancies", resumeID))
+	ctx context.Context, resumeID string, params models.VacancySearchParams) (*models.VacanciesResponse[models.Vacancy], error) {
+	if err := params.Validate(); err != nil {
+		return nil, &Error{Kind: KindValidationFailed, Code: "invalid_search_params", Message: err.Error(), Err: err}
+	}
+
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParamsFromValues(params.Query()).
+			Get(c.apiURL + fmt.Sprintf(constants.SimilarVacancies, resumeID))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get similar vacancies: %w", err)
+		return nil, err
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("unsuccessful response from hh.ru: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
 	var similarVacancies models.VacanciesResponse[models.Vacancy]
 	if err := json.Unmarshal(resp.Body(), &similarVacancies); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
-	return similarVacancies.Items, nil
+	return &similarVacancies, nil
 }
 
 func (c *HHClient) GetShortSuitableVacancies(
-	resumeID string, queryParams map[string]string) ([]models.VacancyShort, error) {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		SetQueryParams(queryParams). // Устанавливаем параметры запроса
-		Get(c.apiURL + fmt.Sprintf("/resumes/%s/similar_vacancies", resumeID))
+	ctx context.Context, resumeID string, params models.VacancySearchParams) (*models.VacanciesResponse[models.VacancyShort], error) {
+	similarVacancies, err := c.GetSuitableVacancies(ctx, resumeID, params)
+	if err != nil {
+		return nil, err
+	}
+	return toShortVacancies(similarVacancies, c.names), nil
+}
+
+// AllShortSuitableVacancies обходит страницы подходящих к резюме вакансий,
+// начиная с params.Page, пока они не закончатся или не будет достигнут предел
+// hh.ru в 2000 результатов. Итерация прекращается на первой ошибке.
+func (c *HHClient) AllShortSuitableVacancies(
+	ctx context.Context, resumeID string, params models.VacancySearchParams) iter.Seq2[models.VacancyShort, error] {
+	return paginate(ctx, params, func(ctx context.Context, params models.VacancySearchParams) (*models.VacanciesResponse[models.VacancyShort], error) {
+		return c.GetShortSuitableVacancies(ctx, resumeID, params)
+	})
+}
+
+// paginate превращает постраничный метод hh.ru в последовательность элементов
+func paginate[T any](
+	ctx context.Context,
+	params models.VacancySearchParams,
+	fetch func(context.Context, models.VacancySearchParams) (*models.VacanciesResponse[T], error),
+) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if params.PerPage == 0 {
+			params.PerPage = models.DefaultPerPage
+		}
+
+		for ; (params.Page+1)*params.PerPage <= models.MaxSearchDepth; params.Page++ {
+			page, err := fetch(ctx, params)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if len(page.Items) == 0 || params.Page+1 >= page.Pages {
+				return
+			}
+		}
+	}
+}
+
+func (c *HHClient) GetFirstSuitableVacancy(ctx context.Context, resumeID string) (*models.Vacancy, error) {
+	firstSimilarVacancy, err := c.GetSuitableVacancies(ctx, resumeID, models.VacancySearchParams{PerPage: 1})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get similar vacancies: %w", err)
 	}
+	if len(firstSimilarVacancy.Items) == 0 {
+		return nil, NewError(KindNotFound, "no_similar_vacancies", "no similar vacancies found")
+	}
+
+	return &firstSimilarVacancy.Items[0], nil
+}
+
+func (c *HHClient) GetFirstShortSuitableVacancy(ctx context.Context, resumeID string) (*models.VacancyShort, error) {
+	firstSimilarVacancy, err := c.GetFirstSuitableVacancy(ctx, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	return firstSimilarVacancy.ToShort(c.names), nil
+}
+
+// PostNegotiationByVacancyID откликается на вакансию. Запрос не повторяется при
+// сбоях: hh.ru мог принять отклик до обрыва соединения.
+func (c *HHClient) PostNegotiationByVacancyID(ctx context.Context, resumeID, vacancyID, message string) error {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetMultipartFormData(map[string]string{
+				"resume_id":  resumeID,
+				"vacancy_id": vacancyID,
+				"message":    message,
+			}).
+			Post(c.apiURL + "/negotiations")
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusCreated { // https://api.hh.ru/negotiations has 201 response code on success
+		return newHHError(resp)
+	}
+
+	return nil
+}
+
+// GetVacancyTest возвращает анкету работодателя. Вопросы hh.ru показывает
+// только авторизованному соискателю, поэтому нужен токен пользователя.
+func (c *HHClient) GetVacancyTest(ctx context.Context, vacancyID string) (*models.Test, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.Vacancy, vacancyID))
+	})
+	if err != nil {
+		return nil, err
+	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("unsuccessful response from hh.ru: %s", resp.String())
+		return nil, newHHError(resp)
 	}
 
-	var similarVacancies models.VacanciesResponse[models.VacancyShort]
-	if err := json.Unmarshal(resp.Body(), &similarVacancies); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	var vacancy models.Vacancy
+	if err := json.Unmarshal(resp.Body(), &vacancy); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+	if vacancy.Test == nil {
+		return nil, NewError(KindNotFound, "no_test", "vacancy has no questionnaire")
+	}
+	if vacancy.Test.Required && len(vacancy.Test.Questions) == 0 {
+		return nil, &Error{
+			Kind:    KindUpstreamUnavailable,
+			Code:    "test_questions_unavailable",
+			Message: "hh.ru did not return the questionnaire questions, answer them on hh.ru",
+			Service: ServiceHH,
+		}
 	}
 
-	return similarVacancies.Items, nil
+	return vacancy.Test, nil
 }
 
-func (c *HHClient) GetFirstSuitableVacancy(resumeID string) (*models.Vacancy, error) {
-	firstSimilarVacancy, err := c.GetSuitableVacancies(resumeID, map[string]string{"per_page": "1"})
+// GetNegotiation возвращает отклик nid с вакансией и резюме
+func (c *HHClient) GetNegotiation(ctx context.Context, nid string) (*models.Negotiation, error) {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(c.apiURL + fmt.Sprintf(constants.NegotiationsNid, nid))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get similar vacancies: %w", err)
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
 	}
-	if len(firstSimilarVacancy) != 1 {
-		return nil, errors.New("vacancies count is not 1")
+
+	var negotiation models.Negotiation
+	if err := json.Unmarshal(resp.Body(), &negotiation); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
 	}
 
-	return &firstSimilarVacancy[0], nil
+	return &negotiation, nil
 }
 
-func (c *HHClient) GetFirstShortSuitableVacancy(resumeID string) (*models.VacancyShort, error) {
-	firstSimilarVacancy, err := c.GetShortSuitableVacancies(resumeID, map[string]string{"per_page": "1"})
+// GetNegotiationMessages возвращает страницу переписки по отклику nid
+func (c *HHClient) GetNegotiationMessages(ctx context.Context, nid string, page, perPage int) (*models.MessagesResponse, error) {
+	query := map[string]string{}
+	if page > 0 {
+		query["page"] = strconv.Itoa(page)
+	}
+	if perPage > 0 {
+		query["per_page"] = strconv.Itoa(perPage)
+	}
+
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetQueryParams(query).
+			Get(c.apiURL + fmt.Sprintf(constants.NegotiationsNidMessage, nid))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get similar vacancies: %w", err)
+		return nil, err
 	}
-	if len(firstSimilarVacancy) != 1 {
-		return nil, errors.New("vacancies count is not 1")
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newHHError(resp)
 	}
 
-	return &firstSimilarVacancy[0], nil
+	var messages models.MessagesResponse
+	if err := json.Unmarshal(resp.Body(), &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse hh.ru response: %w", err)
+	}
+
+	return &messages, nil
 }
 
-func (c *HHClient) PostNegotiationByVacancyID(resumeID, vacancyID, message string) error {
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+c.accessToken).
-		SetMultipartFormData(map[string]string{
-			"resume_id":  resumeID,
-			"vacancy_id": vacancyID,
-			"message":    message,
-		}).
-		Post(c.apiURL + "/negotiations")
+// SendMessage отправляет сообщение работодателю в переписке по отклику nid.
+// Как и отклик, запрос не повторяется при сбоях, чтобы не задвоить сообщение.
+func (c *HHClient) SendMessage(ctx context.Context, nid, text string) error {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetFormData(map[string]string{"message": text}).
+			Post(c.apiURL + fmt.Sprintf(constants.NegotiationsNidMessage, nid))
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка запроса к API hh.ru: %w", err)
+		return err
 	}
-	if resp.StatusCode() != http.StatusCreated { // https://api.hh.ru/negotiations has 201 response code on success
-		logger.Errorf("не удалось создать заявку: %s", resp.String())
-		return fmt.Errorf("не удалось создать заявку: %s", resp.String())
+	if resp.StatusCode() != http.StatusCreated {
+		return newHHError(resp)
 	}
 
 	return nil
 }
 
-func (c *HHClient) SendMessage() {
+// MarkNegotiationsRead отмечает переписку по откликам как прочитанную
+func (c *HHClient) MarkNegotiationsRead(ctx context.Context, nids ...string) error {
+	resp, err := c.authorized(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetFormDataFromValues(url.Values{"topic_id": nids}).
+			Put(c.apiURL + constants.NegotiationsRead)
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent && resp.StatusCode() != http.StatusOK {
+		return newHHError(resp)
+	}
 
+	return nil
 }
 
 // cleanHTML removes HTML tags and unescapes HTML entities from the input string.
@@ -0,0 +1,695 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+)
+
+// recordedRequest is what the fake hh.ru saw of an outgoing request
+type recordedRequest struct {
+	method        string
+	path          string
+	userAgent     string
+	authorization string
+}
+
+// fakeHHServer records every request and answers with the configured handler
+type fakeHHServer struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func newFakeHHServer(t *testing.T, handler http.HandlerFunc) (*fakeHHServer, config.HHConfig) {
+	f := &fakeHHServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, recordedRequest{
+			method:        r.Method,
+			path:          r.URL.Path,
+			userAgent:     r.Header.Get("HH-User-Agent"),
+			authorization: r.Header.Get("Authorization"),
+		})
+		f.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.HHConfig{
+		APIURL:       server.URL,
+		OAuthURL:     server.URL + "/oauth/token",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AppToken:     "app-token",
+		AppName:      "TestApp/1.0",
+		AppContact:   "dev@example.com",
+		Timeout:      5 * time.Second,
+	}
+	return f, cfg
+}
+
+func (f *fakeHHServer) recorded() []recordedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]recordedRequest(nil), f.requests...)
+}
+
+func TestHHClientSendsUserAgent(t *testing.T) {
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			w.Write([]byte(`{"access_token": "user-token", "refresh_token": "refresh", "expires_in": 3600}`))
+		case "/me":
+			w.Write([]byte(`{"id": "42"}`))
+		default:
+			w.Write([]byte(`{"items": []}`))
+		}
+	})
+	client := NewHHClient(cfg, nil)
+	ctx := context.Background()
+
+	token, err := client.ExchangeCodeForToken(ctx, "code")
+	if err != nil {
+		t.Fatalf("ExchangeCodeForToken: %v", err)
+	}
+	if _, err = client.GetUserID(ctx, token.AccessToken); err != nil {
+		t.Fatalf("GetUserID: %v", err)
+	}
+	if _, err = client.WithToken("42", token).GetResumes(ctx); err != nil {
+		t.Fatalf("GetResumes: %v", err)
+	}
+
+	requests := fake.recorded()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	for _, r := range requests {
+		if r.userAgent != "TestApp/1.0 (dev@example.com)" {
+			t.Errorf("%s %s: HH-User-Agent = %q", r.method, r.path, r.userAgent)
+		}
+	}
+}
+
+func TestHHClientUsesAppTokenForPublicEndpoints(t *testing.T) {
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "1", "name": "Go developer"}`))
+	})
+	client := NewHHClient(cfg, nil)
+	ctx := context.Background()
+
+	if _, err := client.GetVacancyByID(ctx, "1"); err != nil {
+		t.Fatalf("GetVacancyByID without user: %v", err)
+	}
+	if _, err := client.WithToken("42", &models.HHToken{AccessToken: "user-token"}).GetVacancyByID(ctx, "1"); err != nil {
+		t.Fatalf("GetVacancyByID with user: %v", err)
+	}
+
+	requests := fake.recorded()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[0].authorization; got != "Bearer app-token" {
+		t.Errorf("public request without user: Authorization = %q, want app token", got)
+	}
+	if got := requests[1].authorization; got != "Bearer user-token" {
+		t.Errorf("public request with user: Authorization = %q, want user token", got)
+	}
+}
+
+func TestHHClientRequiresUserForPrivateEndpoints(t *testing.T) {
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": []}`))
+	})
+
+	_, err := NewHHClient(cfg, nil).GetResumes(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("GetResumes without user: got %v, want ErrUnauthorized", err)
+	}
+	if n := len(fake.recorded()); n != 0 {
+		t.Errorf("sent %d requests without a user token", n)
+	}
+}
+
+func TestHHClientKeepsRefreshedTokenWhenCallerLeaves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			// The browser goes away while hh.ru is already issuing the new pair
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte(`{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 3600}`))
+			return
+		}
+		w.Write([]byte(`{"items": []}`))
+	})
+	tokens := storage.NewMemoryTokenStore()
+	expired := &models.HHToken{AccessToken: "old-access", RefreshToken: "old-refresh", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := tokens.Save("42", expired); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = NewHHClient(cfg, tokens).WithToken("42", expired).GetResumes(ctx)
+
+	stored, err := tokens.Get("42")
+	if err != nil || stored.RefreshToken != "new-refresh" {
+		t.Errorf("stored token = %+v, %v; the refreshed pair was lost", stored, err)
+	}
+}
+
+func TestHHClientRefreshesRejectedToken(t *testing.T) {
+	for name, reject := range map[string]func(w http.ResponseWriter){
+		"401": func(w http.ResponseWriter) { w.WriteHeader(http.StatusUnauthorized) },
+		"403 oauth": func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": [{"type": "oauth", "value": "token_expired"}]}`))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/oauth/token":
+					w.Write([]byte(`{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 3600}`))
+				case r.Header.Get("Authorization") == "Bearer new-access":
+					w.Write([]byte(`{"items": []}`))
+				default:
+					reject(w)
+				}
+			})
+			tokens := storage.NewMemoryTokenStore()
+			// Not expired yet, so only hh.ru's answer can trigger the refresh
+			token := &models.HHToken{AccessToken: "old-access", RefreshToken: "old-refresh", ExpiresAt: time.Now().Add(time.Hour)}
+			if err := tokens.Save("42", token); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := NewHHClient(cfg, tokens).WithToken("42", token).GetResumes(context.Background()); err != nil {
+				t.Fatalf("GetResumes: %v", err)
+			}
+
+			var calls []string
+			for _, r := range fake.recorded() {
+				calls = append(calls, r.method+" "+r.path+" "+r.authorization)
+			}
+			want := []string{
+				"GET /resumes/mine Bearer old-access",
+				"POST /oauth/token ",
+				"GET /resumes/mine Bearer new-access",
+			}
+			if !slices.Equal(calls, want) {
+				t.Errorf("requests = %q, want %q", calls, want)
+			}
+			if stored, _ := tokens.Get("42"); stored == nil || stored.RefreshToken != "new-refresh" {
+				t.Errorf("stored token = %+v, want the refreshed pair", stored)
+			}
+		})
+	}
+}
+
+func TestHHClientRefreshesUsersIndependently(t *testing.T) {
+	release := make(chan struct{})
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			if r.FormValue("refresh_token") == "slow-refresh" {
+				<-release
+			}
+			w.Write([]byte(`{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 3600}`))
+			return
+		}
+		w.Write([]byte(`{"items": []}`))
+	})
+	defer close(release)
+	client := NewHHClient(cfg, storage.NewMemoryTokenStore())
+	expired := func(refresh string) *models.HHToken {
+		return &models.HHToken{AccessToken: "old-access", RefreshToken: refresh, ExpiresAt: time.Now().Add(-time.Minute)}
+	}
+
+	go client.WithToken("slow", expired("slow-refresh")).GetResumes(context.Background())
+	time.Sleep(50 * time.Millisecond) // Let the slow user take the refresh lock
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.WithToken("fast", expired("fast-refresh")).GetResumes(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("GetResumes: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a slow refresh of one user blocked the refresh of another")
+	}
+}
+
+func TestHHClientRetriesOnlyIdempotentRequests(t *testing.T) {
+	for status, want := range map[int]error{
+		http.StatusServiceUnavailable: ErrUpstreamUnavailable,
+		http.StatusTooManyRequests:    ErrRateLimited,
+	} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			})
+			cfg.RetryCount = 2
+			cfg.RetryWait = time.Millisecond
+			cfg.RetryMaxWait = 10 * time.Millisecond
+			client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+			ctx := context.Background()
+
+			if _, err := client.GetResumes(ctx); !errors.Is(err, want) {
+				t.Fatalf("GetResumes: got %v, want %v", err, want)
+			}
+			if err := client.PostNegotiationByVacancyID(ctx, "resume", "vacancy", ""); !errors.Is(err, want) {
+				t.Fatalf("PostNegotiationByVacancyID: got %v, want %v", err, want)
+			}
+
+			attempts := map[string]int{}
+			for _, r := range fake.recorded() {
+				attempts[r.method]++
+			}
+			if attempts[http.MethodGet] != 3 {
+				t.Errorf("GET attempts = %d, want 3", attempts[http.MethodGet])
+			}
+			if attempts[http.MethodPost] != 1 {
+				t.Errorf("POST attempts = %d, want 1", attempts[http.MethodPost])
+			}
+		})
+	}
+}
+
+func TestHHClientHonoursRetryAfter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		first := len(attempts) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"items": []}`))
+	})
+	cfg.RetryCount = 1
+	cfg.RetryWait = time.Millisecond
+	cfg.RetryMaxWait = 5 * time.Second
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+
+	if _, err := client.GetResumes(context.Background()); err != nil {
+		t.Fatalf("GetResumes: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(attempts))
+	}
+	if pause := attempts[1].Sub(attempts[0]); pause < 900*time.Millisecond {
+		t.Errorf("retried after %s, want the second from Retry-After", pause)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for header, want := range map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"0":                             0,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2001 00:00:00 GMT": 0,
+	} {
+		resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+		if header != "" {
+			resp.RawResponse.Header.Set("Retry-After", header)
+		}
+		if got, err := retryAfter(nil, resp); err != nil || got != want {
+			t.Errorf("Retry-After %q: got %s, %v, want %s", header, got, err, want)
+		}
+	}
+
+	resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+	resp.RawResponse.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if got, _ := retryAfter(nil, resp); got < 58*time.Second || got > time.Minute {
+		t.Errorf("Retry-After date a minute ahead: got %s", got)
+	}
+}
+
+func TestHHClientThrottlesEachUser(t *testing.T) {
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": []}`))
+	})
+	cfg.RateLimit = 5
+	cfg.RateBurst = 1
+	client := NewHHClient(cfg, nil)
+	ctx := context.Background()
+
+	alice := client.WithToken("42", &models.HHToken{AccessToken: "alice-token"})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := alice.GetResumes(ctx); err != nil {
+			t.Fatalf("GetResumes %d: %v", i, err)
+		}
+	}
+	// One request at once, then one every 200ms
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Errorf("3 requests took %s, want them spread over 400ms", elapsed)
+	}
+
+	bob := client.WithToken("7", &models.HHToken{AccessToken: "bob-token"})
+	start = time.Now()
+	if _, err := bob.GetResumes(ctx); err != nil {
+		t.Fatalf("GetResumes: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("another user waited %s behind alice's limit", elapsed)
+	}
+
+	waiting, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := alice.GetResumes(waiting); err == nil {
+		t.Error("GetResumes succeeded while throttled past its deadline")
+	}
+}
+
+func TestHHClientSearchVacancies(t *testing.T) {
+	var query url.Values
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{
+			"found": 41, "page": 1, "pages": 3, "per_page": 20,
+			"items": [{"id": "7", "name": "Go developer", "area": {"id": "1", "name": "Москва"}, "employer": {"name": "Acme"}}]
+		}`))
+	})
+
+	found, err := NewHHClient(cfg, nil).SearchVacancies(context.Background(), models.VacancySearchParams{
+		Text:           "golang",
+		Area:           []string{"1", "2"},
+		Salary:         300000,
+		Currency:       "RUR",
+		OnlyWithSalary: true,
+		Page:           1,
+	})
+	if err != nil {
+		t.Fatalf("SearchVacancies: %v", err)
+	}
+
+	if got := query["area"]; len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("area = %v, want [1 2]", got)
+	}
+	for key, want := range map[string]string{"text": "golang", "salary": "300000", "currency": "RUR", "only_with_salary": "true", "page": "1"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if query.Has("per_page") || query.Has("period") {
+		t.Errorf("unset filters were sent: %v", query)
+	}
+
+	if found.Found != 41 || found.Page != 1 || found.Pages != 3 || found.PerPage != 20 {
+		t.Errorf("page metadata = %+v", found)
+	}
+	if len(found.Items) != 1 || found.Items[0].Location != "Москва" || found.Items[0].CompanyName != "Acme" {
+		t.Errorf("items = %+v", found.Items)
+	}
+
+	_, err = NewHHClient(cfg, nil).SearchVacancies(context.Background(), models.VacancySearchParams{Page: 20, PerPage: 100})
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("search beyond 2000 results: got %v, want ErrValidationFailed", err)
+	}
+}
+
+// dictionaryNames resolves IDs from a fixed table, keyed by "dictionary/id"
+type dictionaryNames map[string]string
+
+func (d dictionaryNames) Name(dictionary, id string) (string, bool) {
+	name, ok := d[dictionary+"/"+id]
+	return name, ok
+}
+
+func TestHHClientResolvesDictionaryNames(t *testing.T) {
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/vacancies" {
+			w.Write([]byte(`{"items": [{"id": "7", "area": {"id": "1"}, "employment": {"id": "full"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"id": "7", "employment": {"id": "full"}, "schedule": {"id": "remote", "name": "Удаленная работа"}}`))
+	})
+	names := dictionaryNames{
+		models.DictArea + "/1":          "Москва",
+		models.DictEmployment + "/full": "Полная занятость",
+		models.DictSchedule + "/remote": "Удаленка",
+	}
+	ctx := context.Background()
+
+	found, err := NewHHClient(cfg, nil).WithNames(names).SearchVacancies(ctx, models.VacancySearchParams{})
+	if err != nil {
+		t.Fatalf("SearchVacancies: %v", err)
+	}
+	if item := found.Items[0]; item.Location != "Москва" || item.Employment.Name != "Полная занятость" {
+		t.Errorf("search item = %+v, want names from the dictionaries", item)
+	}
+
+	vacancy, err := NewHHClient(cfg, nil).WithNames(names).GetShortVacancyByID(ctx, "7")
+	if err != nil {
+		t.Fatalf("GetShortVacancyByID: %v", err)
+	}
+	if vacancy.Employment.Name != "Полная занятость" || vacancy.Schedule.Name != "Удаленная работа" {
+		t.Errorf("vacancy = %+v, want the missing name resolved and the sent one kept", vacancy)
+	}
+
+	// Without dictionaries the prompt falls back to IDs
+	found, err = NewHHClient(cfg, nil).SearchVacancies(ctx, models.VacancySearchParams{})
+	if err != nil {
+		t.Fatalf("SearchVacancies: %v", err)
+	}
+	if item := found.Items[0]; item.Location != "1" || !strings.Contains(item.ToString(), "Тип занятости: full") {
+		t.Errorf("search item without dictionaries = %+v", item)
+	}
+}
+
+func TestHHClientAllShortSuitableVacanciesWalksPages(t *testing.T) {
+	const pages = 3
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `{"found": %d, "page": %d, "pages": %d, "per_page": 2, "items": [{"id": "%d-a"}, {"id": "%d-b"}]}`,
+			pages*2, page, pages, page, page)
+	})
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+
+	var ids []string
+	for vacancy, err := range client.AllShortSuitableVacancies(context.Background(), "resume", models.VacancySearchParams{PerPage: 2}) {
+		if err != nil {
+			t.Fatalf("AllShortSuitableVacancies: %v", err)
+		}
+		ids = append(ids, vacancy.ID)
+	}
+
+	want := []string{"0-a", "0-b", "1-a", "1-b", "2-a", "2-b"}
+	if !slices.Equal(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if n := len(fake.recorded()); n != pages {
+		t.Errorf("sent %d requests, want %d", n, pages)
+	}
+}
+
+func TestHHClientAllShortSuitableVacanciesStopsAtDepthLimit(t *testing.T) {
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		items := strings.Repeat(`{"id": "x"},`, 99) + `{"id": "x"}`
+		fmt.Fprintf(w, `{"found": 100000, "pages": 1000, "per_page": 100, "items": [%s]}`, items)
+	})
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+
+	count := 0
+	for _, err := range client.AllShortSuitableVacancies(context.Background(), "resume", models.VacancySearchParams{PerPage: 100}) {
+		if err != nil {
+			t.Fatalf("AllShortSuitableVacancies: %v", err)
+		}
+		count++
+	}
+
+	if count != models.MaxSearchDepth {
+		t.Errorf("got %d vacancies, want %d", count, models.MaxSearchDepth)
+	}
+	if n := len(fake.recorded()); n != models.MaxSearchDepth/100 {
+		t.Errorf("sent %d requests, want %d", n, models.MaxSearchDepth/100)
+	}
+}
+
+func TestHHClientGetNegotiations(t *testing.T) {
+	var queries []url.Values
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		if r.URL.Query().Get("page") == "1" {
+			w.Write([]byte(`{
+				"found": 4, "page": 1, "pages": 2, "per_page": 100,
+				"items": [
+					{"id": "4", "state": {"id": "invitation"}, "has_updates": false, "created_at": "2024-05-03T10:00:00+0300"}
+				]
+			}`))
+			return
+		}
+		w.Write([]byte(`{
+			"found": 4, "page": 0, "pages": 2, "per_page": 100,
+			"items": [
+				{"id": "1", "state": {"id": "invitation"}, "has_updates": true, "created_at": "2024-05-01T10:00:00+0300", "updated_at": "2024-05-20T10:00:00+0300"},
+				{"id": "2", "state": {"id": "response"}, "has_updates": false, "created_at": "2024-05-02T10:00:00+0300", "updated_at": "2024-05-21T10:00:00+0300"},
+				{"id": "3", "state": {"id": "invitation"}, "has_updates": false, "created_at": "2024-04-01T10:00:00+0300", "updated_at": "2024-04-02T10:00:00+0300"}
+			]
+		}`))
+	})
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+
+	negotiations, err := client.GetNegotiations(context.Background(), models.NegotiationsParams{
+		State:       []string{"invitation"},
+		UpdatedFrom: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		VacancyID:   "77",
+		OrderBy:     "updated_at",
+		Order:       "desc",
+		PerPage:     1,
+	})
+	if err != nil {
+		t.Fatalf("GetNegotiations: %v", err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("hh.ru requests = %d, want both pages", len(queries))
+	}
+	for key, want := range map[string]string{"vacancy_id": "77", "order_by": "updated_at", "order": "desc", "per_page": "100"} {
+		if got := queries[0].Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if queries[0].Has("state") || queries[0].Has("updated_from") {
+		t.Errorf("local filters were sent to hh.ru: %v", queries[0])
+	}
+
+	if len(negotiations.Items) != 1 || negotiations.Items[0].ID != "1" {
+		t.Fatalf("items = %+v, want only negotiation 1", negotiations.Items)
+	}
+	if hasUpdates := negotiations.Items[0].HasUpdates; hasUpdates == nil || !*hasUpdates {
+		t.Error("has_updates was lost")
+	}
+	if negotiations.Found != 2 || negotiations.Pages != 2 || negotiations.PerPage != 1 {
+		t.Errorf("found/pages/per_page = %d/%d/%d, want the filtered 2/2/1",
+			negotiations.Found, negotiations.Pages, negotiations.PerPage)
+	}
+
+	second, err := client.GetNegotiations(context.Background(), models.NegotiationsParams{
+		State:   []string{"invitation"},
+		Page:    1,
+		PerPage: 2,
+	})
+	if err != nil {
+		t.Fatalf("GetNegotiations page 1: %v", err)
+	}
+	if len(second.Items) != 1 || second.Items[0].ID != "4" || second.Found != 3 || second.Pages != 2 {
+		t.Errorf("page 1 = %+v, want negotiation 4 of 3 found on 2 pages", second)
+	}
+
+	_, err = client.GetNegotiations(context.Background(), models.NegotiationsParams{OrderBy: "salary"})
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("order_by=salary: got %v, want ErrValidationFailed", err)
+	}
+}
+
+func TestHHClientNegotiationMessages(t *testing.T) {
+	var (
+		sent     string
+		readIDs  []string
+		messages = `{"found": 1, "page": 0, "pages": 1, "per_page": 20, "items": [
+			{"id": "m1", "text": "Когда вам удобно созвониться?", "author": {"participant_type": "employer"}, "viewed_by_me": false}
+		]}`
+	)
+	fake, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/negotiations/n1/messages":
+			w.Write([]byte(messages))
+		case r.Method == http.MethodPost && r.URL.Path == "/negotiations/n1/messages":
+			sent = r.FormValue("message")
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && r.URL.Path == "/negotiations/read":
+			r.ParseForm()
+			readIDs = r.PostForm["topic_id"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	cfg.RetryCount = 2
+	cfg.RetryWait = time.Millisecond
+	cfg.RetryMaxWait = 10 * time.Millisecond
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+	ctx := context.Background()
+
+	thread, err := client.GetNegotiationMessages(ctx, "n1", 0, 0)
+	if err != nil {
+		t.Fatalf("GetNegotiationMessages: %v", err)
+	}
+	if len(thread.Items) != 1 || thread.Items[0].Author.ParticipantType != models.ParticipantEmployer {
+		t.Errorf("messages = %+v", thread.Items)
+	}
+
+	if err := client.SendMessage(ctx, "n1", "Завтра после 14:00"); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if sent != "Завтра после 14:00" {
+		t.Errorf("sent message = %q", sent)
+	}
+
+	if err := client.MarkNegotiationsRead(ctx, "n1"); err != nil {
+		t.Fatalf("MarkNegotiationsRead: %v", err)
+	}
+	if !slices.Equal(readIDs, []string{"n1"}) {
+		t.Errorf("topic_id = %v, want [n1]", readIDs)
+	}
+
+	if err := client.SendMessage(ctx, "missing", "text"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SendMessage to unknown negotiation: got %v, want ErrNotFound", err)
+	}
+	posts := 0
+	for _, r := range fake.recorded() {
+		if r.method == http.MethodPost {
+			posts++
+		}
+	}
+	if posts != 2 {
+		t.Errorf("sent %d POST requests, want 2 without retries", posts)
+	}
+}
+
+func TestHHClientGetVacancyTest(t *testing.T) {
+	_, cfg := newFakeHHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "7", "test": {"required": true, "questions": [
+			{"id": "q1", "type": "text", "text": "Почему мы?", "required": true},
+			{"id": "q2", "type": "multiple", "text": "Стек", "options": [{"id": "go", "text": "Go"}, {"id": "rust", "text": "Rust"}]}
+		]}}`))
+	})
+	client := NewHHClient(cfg, nil).WithToken("42", &models.HHToken{AccessToken: "user-token"})
+
+	test, err := client.GetVacancyTest(context.Background(), "7")
+	if err != nil {
+		t.Fatalf("GetVacancyTest: %v", err)
+	}
+	if len(test.Questions) != 2 || test.Questions[1].Options[0].ID != "go" {
+		t.Fatalf("test = %+v", test)
+	}
+}
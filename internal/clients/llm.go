@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LLMClient отправляет промпт языковой модели и возвращает ответ
+type LLMClient interface {
+	SendPromt(ctx context.Context, req LLMRequest) (string, error)
+}
+
+// LLMStreamClient отдает ответ модели по частям по мере генерации. onDelta
+// получает очередной фрагмент текста; ошибка onDelta прерывает поток.
+type LLMStreamClient interface {
+	LLMClient
+	StreamPromt(ctx context.Context, req LLMRequest, onDelta func(string) error) (*LLMUsage, error)
+}
+
+// LLMUsage — расход токенов на запрос. nil, если сервер его не сообщил.
+type LLMUsage struct {
+	Service          string // Провайдер, например "deepseek"
+	Model            string // Модель из конфигурации провайдера
+	PromptTokens     int    // Токены промпта, включая CacheHitTokens
+	CompletionTokens int
+	CacheHitTokens   int // Токены промпта из кэша провайдера, они дешевле
+}
+
+type usageKey struct{}
+
+// usageRecord — куда клиенты записывают расход токенов
+type usageRecord struct {
+	mu    sync.Mutex
+	usage []LLMUsage
+}
+
+// TrackUsage возвращает контекст, в котором клиенты отметят расход токенов
+// каждого ответа модели, и функцию, возвращающую отмеченное. Ответов бывает
+// несколько, например когда цепочка fallback пробует провайдеров по очереди.
+func TrackUsage(ctx context.Context) (context.Context, func() []LLMUsage) {
+	record := &usageRecord{}
+	return context.WithValue(ctx, usageKey{}, record), func() []LLMUsage {
+		record.mu.Lock()
+		defer record.mu.Unlock()
+		return append([]LLMUsage(nil), record.usage...)
+	}
+}
+
+// recordUsage отмечает расход токенов, если контекст его отслеживает
+func recordUsage(ctx context.Context, usage *LLMUsage) {
+	record, ok := ctx.Value(usageKey{}).(*usageRecord)
+	if !ok || usage == nil {
+		return
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	record.usage = append(record.usage, *usage)
+}
+
+type LLMRequest struct {
+	System    string // задаёт контекст ассистента (определяет "личность" или роль модели)
+	Assistant string // предыдущие ответы модели
+	Content   string // сообщения от пользователя
+	MaxTokens int    // between 1 and 8192, default=4096
+}
+
+// llmTimeout ограничивает запрос к модели, если timeout задан
+func llmTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
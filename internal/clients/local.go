@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+)
+
+const (
+	// chatCompletionsPath дописывается к базовому адресу локального сервера
+	chatCompletionsPath = "/chat/completions"
+	// minCompletionTokens — меньше этого на ответ не оставляем, письмо не поместится
+	minCompletionTokens = 256
+	// messageOverheadTokens — служебные токены шаблона чата на одно сообщение
+	messageOverheadTokens = 8
+)
+
+// NewLocalLLMClient создает клиента локального OpenAI-совместимого сервера
+// (llama.cpp, vLLM, Ollama). Данные резюме не покидают инфраструктуру.
+// Промпт ужимается под размер контекста модели: локальные серверы либо
+// отклоняют длинный запрос, либо молча отрезают его начало вместе с
+// системным промптом. Usage потока запрашивается, только если cfg.StreamUsage.
+func NewLocalLLMClient(cfg config.LocalLLMConfig) *ChatClient {
+	client := NewChatClient(ServiceLocal, cfg.LLMProviderConfig)
+	client.apiURL = chatCompletionsURL(cfg.APIURL)
+	client.contextWindow = cfg.ContextWindow
+	client.streamUsage = cfg.StreamUsage
+	return client
+}
+
+// chatCompletionsURL принимает как базовый адрес http://host:11434/v1, так и
+// полный адрес метода
+func chatCompletionsURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if strings.HasSuffix(baseURL, chatCompletionsPath) {
+		return baseURL
+	}
+	return baseURL + chatCompletionsPath
+}
+
+// estimateTokens оценивает число токенов с запасом: токенизаторы локальных
+// моделей тратят на кириллицу примерно токен на два-три символа
+func estimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	return utf8.RuneCountInString(s)/2 + messageOverheadTokens
+}
+
+// fitContext ужимает запрос под контекст модели: сначала уменьшает лимит
+// ответа, затем обрезает конец пользовательского сообщения. Системный промпт
+// не трогается.
+func fitContext(service string, req LLMRequest, window int) (LLMRequest, error) {
+	if window <= 0 {
+		return req, nil
+	}
+
+	available := window - estimateTokens(req.System) - estimateTokens(req.Assistant)
+	if available-minCompletionTokens <= messageOverheadTokens {
+		return req, &Error{
+			Kind:    KindUpstreamUnavailable,
+			Code:    "context_window_exceeded",
+			Message: "the system prompt does not fit into the model context window",
+			Service: service,
+		}
+	}
+
+	content := estimateTokens(req.Content)
+	if req.MaxTokens <= 0 || content+req.MaxTokens <= available {
+		return req, nil
+	}
+
+	req.MaxTokens = max(minCompletionTokens, min(req.MaxTokens, available-content))
+	if content+req.MaxTokens <= available {
+		return req, nil
+	}
+
+	keep := (available - req.MaxTokens - messageOverheadTokens) * 2
+	runes := []rune(req.Content)
+	logger.Warnf("%s: prompt of %d characters does not fit into the context window of %d tokens, truncated to %d",
+		service, len(runes), window, keep)
+	req.Content = string(runes[:keep])
+	return req, nil
+}
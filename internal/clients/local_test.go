@@ -0,0 +1,162 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+)
+
+// fakeChatServer mimics the /chat/completions endpoint of llama.cpp, vLLM and
+// Ollama and keeps the last request it received
+type fakeChatServer struct {
+	mu            sync.Mutex
+	path          string
+	authorization string
+	request       ChatCompletionRequest
+}
+
+func newFakeChatServer(t *testing.T, handler http.HandlerFunc) (*fakeChatServer, *httptest.Server) {
+	f := &fakeChatServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("fake chat server: bad request body: %v", err)
+		}
+		f.mu.Lock()
+		f.path = r.URL.Path
+		f.authorization = r.Header.Get("Authorization")
+		f.request = request
+		f.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeChatServer) last() (string, string, ChatCompletionRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.path, f.authorization, f.request
+}
+
+func answerChat(content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"choices": []any{map[string]any{"index": 0, "message": map[string]any{"role": "assistant", "content": content}, "finish_reason": "stop"}},
+		})
+	}
+}
+
+func localConfig(baseURL string, window int) config.LocalLLMConfig {
+	return config.LocalLLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIURL: baseURL, Model: "qwen2.5:7b", Timeout: 5 * time.Second},
+		ContextWindow:     window,
+	}
+}
+
+func TestLocalLLMClientTalksChatCompletions(t *testing.T) {
+	fake, server := newFakeChatServer(t, answerChat("Здравствуйте!"))
+	client := NewLocalLLMClient(localConfig(server.URL+"/v1/", 0))
+
+	answer, err := client.SendPromt(context.Background(), LLMRequest{System: "system", Content: "resume", MaxTokens: 512})
+	if err != nil {
+		t.Fatalf("SendPromt: %v", err)
+	}
+	if answer != "Здравствуйте!" {
+		t.Errorf("answer = %q", answer)
+	}
+
+	path, authorization, request := fake.last()
+	if path != "/v1/chat/completions" {
+		t.Errorf("path = %q, want /v1/chat/completions under the base URL", path)
+	}
+	if authorization != "" {
+		t.Errorf("Authorization = %q, a local server needs no key", authorization)
+	}
+	if request.Model != "qwen2.5:7b" || request.Stream || request.MaxTokens != 512 {
+		t.Errorf("request = %+v", request)
+	}
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" || request.Messages[1].Role != "user" {
+		t.Errorf("messages = %+v, want system and user without an empty assistant turn", request.Messages)
+	}
+}
+
+func TestLocalLLMClientFitsContextWindow(t *testing.T) {
+	fake, server := newFakeChatServer(t, answerChat("ok"))
+	const window = 2048
+	client := NewLocalLLMClient(localConfig(server.URL+"/v1/chat/completions", window))
+
+	system := "Ты пишешь сопроводительные письма."
+	content := strings.Repeat("опыт работы ", 2000)
+	if _, err := client.SendPromt(context.Background(), LLMRequest{System: system, Content: content, MaxTokens: 1500}); err != nil {
+		t.Fatalf("SendPromt: %v", err)
+	}
+
+	_, _, request := fake.last()
+	if request.Messages[0].Content != system {
+		t.Errorf("system prompt was changed: %q", request.Messages[0].Content)
+	}
+	sent := request.Messages[1].Content
+	if !strings.HasPrefix(content, sent) || len(sent) >= len(content) {
+		t.Errorf("user message was not truncated from the end: %d of %d bytes", len(sent), len(content))
+	}
+	if request.MaxTokens < minCompletionTokens {
+		t.Errorf("max_tokens = %d, below the minimum of %d", request.MaxTokens, minCompletionTokens)
+	}
+	if used := estimateTokens(system) + estimateTokens(sent) + request.MaxTokens; used > window {
+		t.Errorf("request needs about %d tokens, the window is %d", used, window)
+	}
+
+	// A short prompt is sent as is
+	if _, err := client.SendPromt(context.Background(), LLMRequest{System: system, Content: "резюме", MaxTokens: 1024}); err != nil {
+		t.Fatalf("SendPromt: %v", err)
+	}
+	if _, _, request = fake.last(); request.MaxTokens != 1024 || request.Messages[1].Content != "резюме" {
+		t.Errorf("short prompt was changed: %+v", request)
+	}
+}
+
+func TestLocalLLMClientErrors(t *testing.T) {
+	_, server := newFakeChatServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing-model/chat/completions":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "model \"qwen2.5:7b\" not found, try pulling it first", "type": "api_error"}}`))
+		default:
+			w.Write([]byte(`{"choices": []}`))
+		}
+	})
+
+	_, err := NewLocalLLMClient(localConfig(server.URL+"/missing-model", 0)).SendPromt(context.Background(), LLMRequest{Content: "x"})
+	if e, ok := AsError(err); !ok || e.Kind != KindUpstreamUnavailable || e.Service != ServiceLocal || !strings.Contains(e.Message, "not found") {
+		t.Errorf("missing model: got %v", err)
+	}
+
+	_, err = NewLocalLLMClient(localConfig(server.URL, 0)).SendPromt(context.Background(), LLMRequest{Content: "x"})
+	if !errors.Is(err, &Error{Kind: KindUpstreamUnavailable, Code: "empty_completion"}) {
+		t.Errorf("empty choices: got %v", err)
+	}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	_, err = NewLocalLLMClient(localConfig(down.URL, 0)).SendPromt(context.Background(), LLMRequest{Content: "x"})
+	if !errors.Is(err, &Error{Kind: KindUpstreamUnavailable, Code: "upstream_unreachable"}) {
+		t.Errorf("server down: got %v", err)
+	}
+
+	_, err = NewLocalLLMClient(localConfig(server.URL, 300)).SendPromt(context.Background(), LLMRequest{System: strings.Repeat("я", 1000), Content: "x"})
+	if !errors.Is(err, &Error{Kind: KindUpstreamUnavailable, Code: "context_window_exceeded"}) {
+		t.Errorf("system prompt larger than the window: got %v", err)
+	}
+}
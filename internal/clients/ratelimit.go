@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiterSweepInterval — как часто RateLimiter забывает простаивающих пользователей
+const limiterSweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter — token bucket с отдельным ведром на каждый ключ (пользователя).
+// Запросы без пользователя делят общее ведро с пустым ключом.
+type RateLimiter struct {
+	rate  float64 // токенов в секунду
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter создает RateLimiter, пропускающий rate запросов в секунду
+// с всплесками до burst. При rate <= 0 ограничение выключено.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Wait блокирует до появления свободного токена в ведре key или до отмены ctx
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	if l == nil || l.rate <= 0 {
+		return nil
+	}
+
+	delay := l.reserve(key)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(key)
+		return ctx.Err()
+	}
+}
+
+// reserve забирает токен, уходя в долг, и возвращает время ожидания до его появления
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// cancel возвращает токен, зарезервированный запросом, который не дождался очереди
+func (l *RateLimiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = min(b.tokens+1, l.burst)
+	}
+}
+
+func (l *RateLimiter) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = min(b.tokens+elapsed*l.rate, l.burst)
+	b.last = now
+}
+
+// sweep удаляет полностью восстановившиеся ведра не чаще раза в
+// limiterSweepInterval. Вызывается под l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
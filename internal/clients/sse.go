@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errStreamDone останавливает чтение потока после завершающего события
+var errStreamDone = errors.New("stream done")
+
+// maxSSELine — предел длины строки события, чанки с usage бывают длинными
+const maxSSELine = 1 << 20
+
+// readSSE читает поток server-sent events и передает onEvent тип и данные
+// каждого события. Многострочные data склеиваются через \n, комментарии вроде
+// ": keep-alive" пропускаются. Ошибки onEvent возвращаются как есть (кроме
+// errStreamDone), ошибки чтения — как stream_interrupted.
+func readSSE(service string, r io.Reader, onEvent func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELine)
+
+	var (
+		event string
+		data  []string
+	)
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		err := onEvent(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return ignoreStreamDone(err)
+			}
+		case strings.HasPrefix(line, ":"):
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errStreamInterrupted(service, err)
+	}
+	return ignoreStreamDone(dispatch())
+}
+
+func ignoreStreamDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
+
+// errStreamInterrupted — поток оборвался до завершающего события
+func errStreamInterrupted(service string, err error) error {
+	return &Error{
+		Kind:    KindUpstreamUnavailable,
+		Code:    "stream_interrupted",
+		Message: "the response stream ended unexpectedly",
+		Service: service,
+		Err:     err,
+	}
+}
@@ -0,0 +1,644 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config — конфигурация приложения
+type Config struct {
+	Server  ServerConfig  `yaml:"server"`
+	HH      HHConfig      `yaml:"hh"`
+	LLM     LLMConfig     `yaml:"llm"`
+	Session SessionConfig `yaml:"session"`
+	Storage StorageConfig `yaml:"storage"`
+	Log     LogConfig     `yaml:"log"`
+}
+
+// ServerConfig — параметры HTTP-сервера
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`                // HTTP_ADDR
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // HTTP_READ_TIMEOUT
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // HTTP_READ_HEADER_TIMEOUT
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // HTTP_WRITE_TIMEOUT, с запасом на генерацию письма
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // HTTP_IDLE_TIMEOUT
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`    // HTTP_SHUTDOWN_TIMEOUT, время на завершение запросов
+}
+
+// HHConfig — параметры доступа к hh.ru
+type HHConfig struct {
+	URL          string        `yaml:"url"`           // HH_URL, адрес страницы авторизации
+	APIURL       string        `yaml:"api_url"`       // HH_API_URL
+	OAuthURL     string        `yaml:"oauth_url"`     // HH_OAUTH_URL, адрес выдачи токенов
+	ClientID     string        `yaml:"client_id"`     // HH_CLIENT_ID
+	ClientSecret string        `yaml:"client_secret"` // HH_CLIENT_SECRET
+	RedirectURI  string        `yaml:"redirect_uri"`  // HH_REDIRECT_URI
+	AppToken     string        `yaml:"app_token"`     // HH_APP_TOKEN, для публичных методов без пользователя
+	AppName      string        `yaml:"app_name"`      // HH_APP_NAME, название и версия для HH-User-Agent
+	AppContact   string        `yaml:"app_contact"`   // HH_APP_CONTACT, email разработчика для HH-User-Agent
+	Timeout      time.Duration `yaml:"timeout"`       // HH_TIMEOUT, на одну операцию с учетом обновления токена и повторов
+
+	RequestTimeout time.Duration `yaml:"request_timeout"` // HH_REQUEST_TIMEOUT, на одну попытку запроса
+	RetryCount     int           `yaml:"retry_count"`     // HH_RETRY_COUNT, повторы GET-запросов при 429/5xx, 0 — без повторов
+	RetryWait      time.Duration `yaml:"retry_wait"`      // HH_RETRY_WAIT, начальная пауза между повторами
+	RetryMaxWait   time.Duration `yaml:"retry_max_wait"`  // HH_RETRY_MAX_WAIT, предел паузы, в том числе из Retry-After
+	RateLimit      float64       `yaml:"rate_limit"`      // HH_RATE_LIMIT, запросов в секунду на пользователя, 0 — без ограничения
+	RateBurst      int           `yaml:"rate_burst"`      // HH_RATE_BURST
+
+	DictionariesCache string        `yaml:"dictionaries_cache"` // HH_DICTIONARIES_CACHE, файл снимка справочников, пусто — без кэша
+	DictionariesTTL   time.Duration `yaml:"dictionaries_ttl"`   // HH_DICTIONARIES_TTL, период обновления справочников
+}
+
+// LLMConfig — параметры генерации текста. Используются только провайдеры,
+// для которых задан ключ (для local — адрес).
+type LLMConfig struct {
+	MaxTokens int               `yaml:"max_tokens"` // LLM_MAX_TOKENS
+	Provider  string            `yaml:"provider"`   // LLM_PROVIDER, по умолчанию первый настроенный
+	DeepSeek  LLMProviderConfig `yaml:"deepseek"`   // DEEPSEEK_*
+	OpenAI    LLMProviderConfig `yaml:"openai"`     // OPENAI_*
+	Anthropic LLMProviderConfig `yaml:"anthropic"`  // ANTHROPIC_*
+	Local     LocalLLMConfig    `yaml:"local"`      // LOCAL_LLM_*, OpenAI-совместимый сервер без ключа
+	Fallback  LLMFallbackConfig `yaml:"fallback"`
+	Usage     LLMUsageConfig    `yaml:"usage"`
+}
+
+// LLMUsageConfig — цены моделей, месячные бюджеты пользователей в долларах и
+// хранение расхода. Модели без цены считаются бесплатными, например локальные.
+type LLMUsageConfig struct {
+	Prices        []string `yaml:"prices"`         // LLM_PRICES через запятую: модель=промпт/кэш/ответ за миллион токенов
+	MonthlyBudget float64  `yaml:"monthly_budget"` // LLM_MONTHLY_BUDGET на пользователя, 0 — без ограничения
+	UserBudgets   []string `yaml:"user_budgets"`   // LLM_USER_BUDGETS через запятую: ID пользователя=бюджет
+	Backend       string   `yaml:"backend"`        // LLM_USAGE_BACKEND: file или memory, с memory расход обнуляется при перезапуске
+	File          string   `yaml:"file"`           // LLM_USAGE_FILE, для backend=file
+	Months        int      `yaml:"months"`         // LLM_USAGE_MONTHS, сколько месяцев хранить, включая текущий
+}
+
+// LLMPrice — цена модели в долларах за миллион токенов
+type LLMPrice struct {
+	Prompt     float64 // Промпт мимо кэша
+	CacheHit   float64 // Промпт из кэша провайдера
+	Completion float64 // Ответ модели
+}
+
+// PriceTable разбирает цены вида deepseek-chat=0.27/0.07/1.10
+func (c LLMUsageConfig) PriceTable() (map[string]LLMPrice, error) {
+	prices := make(map[string]LLMPrice, len(c.Prices))
+	for _, entry := range c.Prices {
+		model, price, err := parseEntry(entry, 3)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_PRICES (llm.usage.prices): %q must be model=prompt/cache_hit/completion like deepseek-chat=0.27/0.07/1.10", entry)
+		}
+		prices[model] = LLMPrice{Prompt: price[0], CacheHit: price[1], Completion: price[2]}
+	}
+	return prices, nil
+}
+
+// Budgets разбирает бюджеты отдельных пользователей вида 12345=20
+func (c LLMUsageConfig) Budgets() (map[string]float64, error) {
+	budgets := make(map[string]float64, len(c.UserBudgets))
+	for _, entry := range c.UserBudgets {
+		userID, budget, err := parseEntry(entry, 1)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_USER_BUDGETS (llm.usage.user_budgets): %q must be user_id=budget like 12345=20", entry)
+		}
+		budgets[userID] = budget[0]
+	}
+	return budgets, nil
+}
+
+// parseEntry разбирает запись ключ=число/число/... с n неотрицательными числами.
+// Ключ отделяется последним "=", в имени модели бывают любые символы.
+func parseEntry(entry string, n int) (string, []float64, error) {
+	entry = strings.TrimSpace(entry)
+	i := strings.LastIndex(entry, "=")
+	if i <= 0 {
+		return "", nil, errors.New("missing key")
+	}
+
+	parts := strings.Split(entry[i+1:], "/")
+	if len(parts) != n {
+		return "", nil, fmt.Errorf("want %d values", n)
+	}
+	values := make([]float64, 0, n)
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v < 0 {
+			return "", nil, fmt.Errorf("invalid value %q", part)
+		}
+		values = append(values, v)
+	}
+	return strings.TrimSpace(entry[:i]), values, nil
+}
+
+// LLMFallbackName — имя провайдера, который перебирает цепочку LLMFallbackConfig
+const LLMFallbackName = "fallback"
+
+// LLMFallbackConfig — цепочка провайдеров, которые пробуются по очереди, пока
+// один не ответит. Провайдер, упавший Failures раз подряд, пропускается на
+// Cooldown, затем пробуется снова одним запросом.
+type LLMFallbackConfig struct {
+	Chain    []string      `yaml:"chain"`    // LLM_FALLBACK_CHAIN через запятую: deepseek:20s,openai:40s,local
+	Failures int           `yaml:"failures"` // LLM_BREAKER_FAILURES
+	Cooldown time.Duration `yaml:"cooldown"` // LLM_BREAKER_COOLDOWN
+}
+
+// LLMFallbackStep — звено цепочки: провайдер и его тайм-аут, 0 — тайм-аут провайдера
+type LLMFallbackStep struct {
+	Name    string
+	Timeout time.Duration
+}
+
+// Steps разбирает записи цепочки вида имя[:тайм-аут]
+func (c LLMFallbackConfig) Steps() ([]LLMFallbackStep, error) {
+	steps := make([]LLMFallbackStep, 0, len(c.Chain))
+	for _, entry := range c.Chain {
+		name, timeout, hasTimeout := strings.Cut(strings.TrimSpace(entry), ":")
+		step := LLMFallbackStep{Name: name}
+		if hasTimeout {
+			parsed, err := time.ParseDuration(timeout)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("LLM_FALLBACK_CHAIN (llm.fallback.chain): %q must be name or name:timeout like deepseek:20s", entry)
+			}
+			step.Timeout = parsed
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// LLMProviderConfig — параметры доступа к API языковой модели
+type LLMProviderConfig struct {
+	APIURL  string        `yaml:"api_url"`
+	APIKey  string        `yaml:"api_key"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// LocalLLMConfig — параметры локального OpenAI-совместимого сервера: llama.cpp,
+// vLLM, Ollama. APIURL — базовый адрес вроде http://localhost:11434/v1, ключ
+// необязателен. StreamUsage выключен по умолчанию: не все серверы понимают
+// stream_options, а некоторые отклоняют запрос с ним.
+type LocalLLMConfig struct {
+	LLMProviderConfig `yaml:",inline"`
+	ContextWindow     int  `yaml:"context_window"` // LOCAL_LLM_CONTEXT_WINDOW, размер контекста модели в токенах, 0 — не ограничивать
+	StreamUsage       bool `yaml:"stream_usage"`   // LOCAL_LLM_STREAM_USAGE, просить usage в конце потока
+}
+
+// Providers возвращает настроенных провайдеров по именам в порядке приоритета
+func (c LLMConfig) Providers() []NamedLLMProvider {
+	all := []NamedLLMProvider{
+		{"deepseek", "DEEPSEEK", c.DeepSeek},
+		{"openai", "OPENAI", c.OpenAI},
+		{"anthropic", "ANTHROPIC", c.Anthropic},
+		{"local", "LOCAL_LLM", c.Local.LLMProviderConfig},
+	}
+
+	var configured []NamedLLMProvider
+	for _, p := range all {
+		if p.Config.APIKey != "" || (p.Name == "local" && p.Config.APIURL != "") {
+			configured = append(configured, p)
+		}
+	}
+	return configured
+}
+
+// NamedLLMProvider — настроенный провайдер и его имя
+type NamedLLMProvider struct {
+	Name      string
+	EnvPrefix string // Префикс переменных окружения, например OPENAI
+	Config    LLMProviderConfig
+}
+
+// SessionConfig — параметры хранения сессий
+type SessionConfig struct {
+	Backend string        `yaml:"backend"` // SESSION_BACKEND: memory или file
+	Dir     string        `yaml:"dir"`     // SESSION_DIR, каталог для backend=file
+	Secrets []string      `yaml:"secrets"` // SESSION_SECRETS через запятую, первый — текущий
+	Secure  bool          `yaml:"secure"`  // SESSION_SECURE, cookie только по HTTPS
+	MaxAge  time.Duration `yaml:"max_age"` // SESSION_MAX_AGE
+}
+
+// StorageConfig — где хранятся OAuth-токены hh.ru и выданные API-ключи
+type StorageConfig struct {
+	Backend     string `yaml:"backend"`       // STORAGE_BACKEND: file или memory
+	TokensFile  string `yaml:"tokens_file"`   // STORAGE_TOKENS_FILE, для backend=file
+	APIKeysFile string `yaml:"api_keys_file"` // STORAGE_API_KEYS_FILE, для backend=file
+}
+
+// LogConfig — параметры логирования
+type LogConfig struct {
+	Level string `yaml:"level"` // LOG_LEVEL
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		HH: HHConfig{
+			URL:      "https://hh.ru",
+			APIURL:   "https://api.hh.ru",
+			OAuthURL: "https://hh.ru/oauth/token",
+			AppName:  "CoverLetterGenerator/1.0",
+			Timeout:  30 * time.Second,
+
+			RequestTimeout: 10 * time.Second,
+			RetryCount:     3,
+			RetryWait:      500 * time.Millisecond,
+			RetryMaxWait:   10 * time.Second,
+			RateLimit:      5,
+			RateBurst:      10,
+
+			DictionariesCache: "data/hh_dictionaries.json",
+			DictionariesTTL:   24 * time.Hour,
+		},
+		LLM: LLMConfig{
+			MaxTokens: 2048,
+			DeepSeek: LLMProviderConfig{
+				APIURL:  "https://api.deepseek.com/chat/completions",
+				Model:   "deepseek-chat",
+				Timeout: 90 * time.Second,
+			},
+			OpenAI: LLMProviderConfig{
+				APIURL:  "https://api.openai.com/v1/chat/completions",
+				Model:   "gpt-4o-mini",
+				Timeout: 90 * time.Second,
+			},
+			Anthropic: LLMProviderConfig{
+				APIURL:  "https://api.anthropic.com/v1/messages",
+				Model:   "claude-3-5-haiku-latest",
+				Timeout: 90 * time.Second,
+			},
+			Local: LocalLLMConfig{
+				LLMProviderConfig: LLMProviderConfig{Timeout: 2 * time.Minute},
+				ContextWindow:     8192,
+			},
+			Fallback: LLMFallbackConfig{
+				Failures: 3,
+				Cooldown: time.Minute,
+			},
+			Usage: LLMUsageConfig{
+				Backend: "file",
+				File:    "data/llm_usage.jsonl",
+				Months:  12,
+			},
+		},
+		Session: SessionConfig{
+			Backend: "memory",
+			MaxAge:  7 * 24 * time.Hour,
+		},
+		Storage: StorageConfig{
+			Backend:     "file",
+			TokensFile:  "data/hh_tokens.json",
+			APIKeysFile: "data/api_keys.json",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+}
+
+// Load собирает конфигурацию. Значения по умолчанию переопределяются YAML-файлом
+// (path или CONFIG_FILE), затем переменными окружения. Файл .env необязателен и
+// не перекрывает уже заданные переменные окружения.
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	env := envReader{}
+
+	env.string(&c.Server.Addr, "HTTP_ADDR")
+	env.duration(&c.Server.ReadTimeout, "HTTP_READ_TIMEOUT")
+	env.duration(&c.Server.ReadHeaderTimeout, "HTTP_READ_HEADER_TIMEOUT")
+	env.duration(&c.Server.WriteTimeout, "HTTP_WRITE_TIMEOUT")
+	env.duration(&c.Server.IdleTimeout, "HTTP_IDLE_TIMEOUT")
+	env.duration(&c.Server.ShutdownTimeout, "HTTP_SHUTDOWN_TIMEOUT")
+
+	env.string(&c.HH.URL, "HH_URL")
+	env.string(&c.HH.APIURL, "HH_API_URL")
+	env.string(&c.HH.OAuthURL, "HH_OAUTH_URL")
+	env.string(&c.HH.ClientID, "HH_CLIENT_ID")
+	env.string(&c.HH.ClientSecret, "HH_CLIENT_SECRET")
+	env.string(&c.HH.RedirectURI, "HH_REDIRECT_URI")
+	env.string(&c.HH.AppToken, "HH_APP_TOKEN")
+	env.string(&c.HH.AppName, "HH_APP_NAME")
+	env.string(&c.HH.AppContact, "HH_APP_CONTACT")
+	env.duration(&c.HH.Timeout, "HH_TIMEOUT")
+	env.duration(&c.HH.RequestTimeout, "HH_REQUEST_TIMEOUT")
+	env.int(&c.HH.RetryCount, "HH_RETRY_COUNT")
+	env.duration(&c.HH.RetryWait, "HH_RETRY_WAIT")
+	env.duration(&c.HH.RetryMaxWait, "HH_RETRY_MAX_WAIT")
+	env.float(&c.HH.RateLimit, "HH_RATE_LIMIT")
+	env.int(&c.HH.RateBurst, "HH_RATE_BURST")
+	env.string(&c.HH.DictionariesCache, "HH_DICTIONARIES_CACHE")
+	env.duration(&c.HH.DictionariesTTL, "HH_DICTIONARIES_TTL")
+
+	env.int(&c.LLM.MaxTokens, "LLM_MAX_TOKENS")
+	env.string(&c.LLM.Provider, "LLM_PROVIDER")
+	env.llmProvider(&c.LLM.DeepSeek, "DEEPSEEK")
+	env.llmProvider(&c.LLM.OpenAI, "OPENAI")
+	env.llmProvider(&c.LLM.Anthropic, "ANTHROPIC")
+	env.llmProvider(&c.LLM.Local.LLMProviderConfig, "LOCAL_LLM")
+	env.int(&c.LLM.Local.ContextWindow, "LOCAL_LLM_CONTEXT_WINDOW")
+	env.bool(&c.LLM.Local.StreamUsage, "LOCAL_LLM_STREAM_USAGE")
+	env.list(&c.LLM.Fallback.Chain, "LLM_FALLBACK_CHAIN")
+	env.int(&c.LLM.Fallback.Failures, "LLM_BREAKER_FAILURES")
+	env.duration(&c.LLM.Fallback.Cooldown, "LLM_BREAKER_COOLDOWN")
+	env.list(&c.LLM.Usage.Prices, "LLM_PRICES")
+	env.float(&c.LLM.Usage.MonthlyBudget, "LLM_MONTHLY_BUDGET")
+	env.list(&c.LLM.Usage.UserBudgets, "LLM_USER_BUDGETS")
+	env.string(&c.LLM.Usage.Backend, "LLM_USAGE_BACKEND")
+	env.string(&c.LLM.Usage.File, "LLM_USAGE_FILE")
+	env.int(&c.LLM.Usage.Months, "LLM_USAGE_MONTHS")
+
+	env.string(&c.Session.Backend, "SESSION_BACKEND")
+	env.string(&c.Session.Dir, "SESSION_DIR")
+	env.list(&c.Session.Secrets, "SESSION_SECRETS")
+	env.bool(&c.Session.Secure, "SESSION_SECURE")
+	env.duration(&c.Session.MaxAge, "SESSION_MAX_AGE")
+
+	env.string(&c.Storage.Backend, "STORAGE_BACKEND")
+	env.string(&c.Storage.TokensFile, "STORAGE_TOKENS_FILE")
+	env.string(&c.Storage.APIKeysFile, "STORAGE_API_KEYS_FILE")
+
+	env.string(&c.Log.Level, "LOG_LEVEL")
+
+	return env.err()
+}
+
+// Validate проверяет обязательные параметры и допустимость значений
+func (c *Config) Validate() error {
+	var errs []error
+
+	required := []struct {
+		value string
+		name  string
+	}{
+		{c.Server.Addr, "HTTP_ADDR (server.addr)"},
+		{c.HH.URL, "HH_URL (hh.url)"},
+		{c.HH.APIURL, "HH_API_URL (hh.api_url)"},
+		{c.HH.OAuthURL, "HH_OAUTH_URL (hh.oauth_url)"},
+		{c.HH.ClientID, "HH_CLIENT_ID (hh.client_id)"},
+		{c.HH.ClientSecret, "HH_CLIENT_SECRET (hh.client_secret)"},
+		{c.HH.AppName, "HH_APP_NAME (hh.app_name)"},
+		{c.HH.AppContact, "HH_APP_CONTACT (hh.app_contact)"},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", r.name))
+		}
+	}
+
+	timeouts := []struct {
+		value time.Duration
+		name  string
+	}{
+		{c.Server.ReadTimeout, "HTTP_READ_TIMEOUT (server.read_timeout)"},
+		{c.Server.ReadHeaderTimeout, "HTTP_READ_HEADER_TIMEOUT (server.read_header_timeout)"},
+		{c.Server.WriteTimeout, "HTTP_WRITE_TIMEOUT (server.write_timeout)"},
+		{c.Server.IdleTimeout, "HTTP_IDLE_TIMEOUT (server.idle_timeout)"},
+		{c.HH.Timeout, "HH_TIMEOUT (hh.timeout)"},
+		{c.HH.RequestTimeout, "HH_REQUEST_TIMEOUT (hh.request_timeout)"},
+		{c.HH.RetryWait, "HH_RETRY_WAIT (hh.retry_wait)"},
+		{c.HH.RetryMaxWait, "HH_RETRY_MAX_WAIT (hh.retry_max_wait)"},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", t.name))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("HTTP_SHUTDOWN_TIMEOUT (server.shutdown_timeout) must be positive"))
+	}
+
+	if c.HH.RetryCount < 0 {
+		errs = append(errs, errors.New("HH_RETRY_COUNT (hh.retry_count) must not be negative"))
+	}
+	if c.HH.RetryMaxWait < c.HH.RetryWait {
+		errs = append(errs, errors.New("HH_RETRY_MAX_WAIT (hh.retry_max_wait) must not be less than HH_RETRY_WAIT (hh.retry_wait)"))
+	}
+	if c.HH.DictionariesTTL <= 0 {
+		errs = append(errs, errors.New("HH_DICTIONARIES_TTL (hh.dictionaries_ttl) must be positive"))
+	}
+	if c.HH.RateLimit < 0 {
+		errs = append(errs, errors.New("HH_RATE_LIMIT (hh.rate_limit) must not be negative"))
+	}
+	if c.HH.RateLimit > 0 && c.HH.RateBurst < 1 {
+		errs = append(errs, errors.New("HH_RATE_BURST (hh.rate_burst) must be at least 1"))
+	}
+
+	if c.LLM.MaxTokens <= 0 {
+		errs = append(errs, errors.New("LLM_MAX_TOKENS (llm.max_tokens) must be positive"))
+	}
+	errs = append(errs, c.LLM.validateProviders(c.Server.WriteTimeout)...)
+	errs = append(errs, c.LLM.Usage.validate()...)
+
+	switch c.Session.Backend {
+	case "memory", "file":
+	default:
+		errs = append(errs, fmt.Errorf("SESSION_BACKEND (session.backend) must be memory or file, got %q", c.Session.Backend))
+	}
+	if c.Session.MaxAge <= 0 {
+		errs = append(errs, errors.New("SESSION_MAX_AGE (session.max_age) must be positive"))
+	}
+	errs = append(errs, c.Storage.validate()...)
+
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL (log.level): %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateProviders проверяет, что есть хотя бы один провайдер, у каждого
+// заданы адрес и модель, а LLM_PROVIDER указывает на настроенного. Ответ
+// провайдера должен укладываться в writeTimeout, иначе он не дойдет до клиента.
+func (c LLMConfig) validateProviders(writeTimeout time.Duration) []error {
+	var errs []error
+
+	providers := c.Providers()
+	if len(providers) == 0 {
+		return []error{errors.New("configure at least one LLM provider: DEEPSEEK_API_KEY, OPENAI_API_KEY, ANTHROPIC_API_KEY or LOCAL_LLM_API_URL")}
+	}
+
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name)
+		prefix := p.EnvPrefix
+		if p.Config.APIURL == "" {
+			errs = append(errs, fmt.Errorf("%s_API_URL (llm.%s.api_url) is required", prefix, p.Name))
+		}
+		if p.Config.Model == "" {
+			errs = append(errs, fmt.Errorf("%s_MODEL (llm.%s.model) is required", prefix, p.Name))
+		}
+		if p.Config.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("%s_TIMEOUT (llm.%s.timeout) must not be negative", prefix, p.Name))
+		}
+		if writeTimeout > 0 && (p.Config.Timeout == 0 || p.Config.Timeout > writeTimeout) {
+			errs = append(errs, fmt.Errorf("%s_TIMEOUT (llm.%s.timeout) must be set and not exceed HTTP_WRITE_TIMEOUT (server.write_timeout) %s",
+				prefix, p.Name, writeTimeout))
+		}
+	}
+
+	if c.Local.ContextWindow < 0 {
+		errs = append(errs, errors.New("LOCAL_LLM_CONTEXT_WINDOW (llm.local.context_window) must not be negative"))
+	}
+
+	errs = append(errs, c.validateFallback(providers, writeTimeout)...)
+	if len(c.Fallback.Chain) > 0 {
+		names = append(names, LLMFallbackName)
+	}
+
+	if c.Provider != "" && !slices.Contains(names, c.Provider) {
+		errs = append(errs, fmt.Errorf("LLM_PROVIDER (llm.provider) must be one of the configured providers %v, got %q", names, c.Provider))
+	}
+	return errs
+}
+
+// validate проверяет цены и бюджеты. Бюджет без цен ничего не ограничивает.
+func (c LLMUsageConfig) validate() []error {
+	var errs []error
+	if _, err := c.Budgets(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MonthlyBudget < 0 {
+		errs = append(errs, errors.New("LLM_MONTHLY_BUDGET (llm.usage.monthly_budget) must not be negative"))
+	}
+	switch c.Backend {
+	case "memory":
+	case "file":
+		if c.File == "" {
+			errs = append(errs, errors.New("LLM_USAGE_FILE (llm.usage.file) is required for LLM_USAGE_BACKEND=file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LLM_USAGE_BACKEND (llm.usage.backend) must be file or memory, got %q", c.Backend))
+	}
+	if c.Months < 1 {
+		errs = append(errs, errors.New("LLM_USAGE_MONTHS (llm.usage.months) must be at least 1"))
+	}
+	prices, err := c.PriceTable()
+	if err != nil {
+		errs = append(errs, err)
+	} else if len(prices) == 0 && (c.MonthlyBudget > 0 || len(c.UserBudgets) > 0) {
+		errs = append(errs, errors.New("LLM_PRICES (llm.usage.prices) is required to enforce budgets"))
+	}
+	return errs
+}
+
+// validate проверяет, что для файлового хранилища заданы пути
+func (c StorageConfig) validate() []error {
+	var errs []error
+	switch c.Backend {
+	case "memory":
+	case "file":
+		if c.TokensFile == "" {
+			errs = append(errs, errors.New("STORAGE_TOKENS_FILE (storage.tokens_file) is required for STORAGE_BACKEND=file"))
+		}
+		if c.APIKeysFile == "" {
+			errs = append(errs, errors.New("STORAGE_API_KEYS_FILE (storage.api_keys_file) is required for STORAGE_BACKEND=file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND (storage.backend) must be file or memory, got %q", c.Backend))
+	}
+	return errs
+}
+
+// validateFallback проверяет, что цепочка состоит из разных настроенных
+// провайдеров и вся укладывается в writeTimeout: звенья пробуются друг за
+// другом, и каждое может ждать свой тайм-аут целиком.
+func (c LLMConfig) validateFallback(providers []NamedLLMProvider, writeTimeout time.Duration) []error {
+	if len(c.Fallback.Chain) == 0 {
+		return nil
+	}
+
+	var errs []error
+	steps, err := c.Fallback.Steps()
+	if err != nil {
+		return []error{err}
+	}
+	seen := make(map[string]bool, len(steps))
+	var total time.Duration
+	for _, step := range steps {
+		i := slices.IndexFunc(providers, func(p NamedLLMProvider) bool { return p.Name == step.Name })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("LLM_FALLBACK_CHAIN (llm.fallback.chain): provider %q is not configured", step.Name))
+		} else {
+			timeout := providers[i].Config.Timeout
+			if step.Timeout > 0 && (timeout == 0 || step.Timeout < timeout) {
+				timeout = step.Timeout
+			}
+			total += timeout
+		}
+		if seen[step.Name] {
+			errs = append(errs, fmt.Errorf("LLM_FALLBACK_CHAIN (llm.fallback.chain): provider %q is listed twice", step.Name))
+		}
+		seen[step.Name] = true
+	}
+	if writeTimeout > 0 && total > writeTimeout {
+		errs = append(errs, fmt.Errorf("LLM_FALLBACK_CHAIN (llm.fallback.chain): step timeouts add up to %s, more than HTTP_WRITE_TIMEOUT (server.write_timeout) %s; "+
+			"set shorter step timeouts like deepseek:40s", total, writeTimeout))
+	}
+	if c.Fallback.Failures < 1 {
+		errs = append(errs, errors.New("LLM_BREAKER_FAILURES (llm.fallback.failures) must be at least 1"))
+	}
+	if c.Fallback.Cooldown <= 0 {
+		errs = append(errs, errors.New("LLM_BREAKER_COOLDOWN (llm.fallback.cooldown) must be positive"))
+	}
+	return errs
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envPrefixes cover every variable Load reads
+var envPrefixes = []string{
+	"CONFIG_FILE", "HTTP_", "HH_", "LLM_", "DEEPSEEK_", "OPENAI_", "ANTHROPIC_", "LOCAL_LLM_", "SESSION_", "STORAGE_", "LOG_",
+}
+
+// isolate runs the test in an empty directory with none of the application's
+// variables set, and restores both afterwards. Load writes the variables of
+// .env into the process environment, so they are cleared as well.
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	saved := map[string]string{}
+	clear := func() {
+		for _, entry := range os.Environ() {
+			key, value, _ := strings.Cut(entry, "=")
+			for _, prefix := range envPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					if _, ok := saved[key]; !ok {
+						saved[key] = value
+					}
+					os.Unsetenv(key)
+				}
+			}
+		}
+	}
+	clear()
+	restore := make(map[string]string, len(saved))
+	for key, value := range saved {
+		restore[key] = value
+	}
+	t.Cleanup(func() {
+		clear()
+		for key, value := range restore {
+			os.Setenv(key, value)
+		}
+	})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, "config.yaml"), `
+server:
+  addr: ":9000"
+hh:
+  client_id: yaml-id
+  client_secret: yaml-secret
+  app_name: YamlApp/1.0
+  app_contact: yaml@example.com
+llm:
+  max_tokens: 500
+  deepseek:
+    api_key: yaml-key
+`)
+	writeFile(t, filepath.Join(dir, ".env"), "HH_CLIENT_SECRET=dotenv-secret\nHH_APP_NAME=DotenvApp/1.0\n")
+	t.Setenv("HH_APP_NAME", "EnvApp/1.0")
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	for name, tc := range map[string]struct{ got, want any }{
+		"default":          {cfg.HH.APIURL, "https://api.hh.ru"},
+		"default duration": {cfg.Server.WriteTimeout, 2 * time.Minute},
+		"yaml":             {cfg.Server.Addr, ":9000"},
+		"yaml int":         {cfg.LLM.MaxTokens, 500},
+		"yaml only":        {cfg.HH.ClientID, "yaml-id"},
+		".env over yaml":   {cfg.HH.ClientSecret, "dotenv-secret"},
+		"env over .env":    {cfg.HH.AppName, "EnvApp/1.0"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadFindsFileThroughEnv(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "app.yaml")
+	writeFile(t, path, "hh:\n  client_id: from-file\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("HH_CLIENT_SECRET", "secret")
+	t.Setenv("HH_APP_CONTACT", "dev@example.com")
+	t.Setenv("DEEPSEEK_API_KEY", "key")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.HH.ClientID != "from-file" {
+		t.Errorf("client id = %q, want the value from CONFIG_FILE", cfg.HH.ClientID)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		yaml string
+		env  map[string]string
+		want string
+	}{
+		"unknown yaml field": {
+			yaml: "hh:\n  client_key: typo\n",
+			want: "field client_key not found",
+		},
+		"malformed env": {
+			env:  map[string]string{"HH_RETRY_COUNT": "three"},
+			want: "HH_RETRY_COUNT must be an integer",
+		},
+		"invalid result": {
+			env:  map[string]string{"HH_RETRY_COUNT": "-1"},
+			want: "HH_RETRY_COUNT (hh.retry_count) must not be negative",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := isolate(t)
+			path := filepath.Join(dir, "config.yaml")
+			writeFile(t, path, "hh:\n  client_id: id\n  client_secret: secret\n  app_contact: dev@example.com\nllm:\n  deepseek:\n    api_key: key\n")
+			if tc.yaml != "" {
+				writeFile(t, path, tc.yaml)
+			}
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load: got %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// validConfig returns defaults with the required values filled in
+func validConfig() *Config {
+	cfg := Default()
+	cfg.HH.ClientID = "id"
+	cfg.HH.ClientSecret = "secret"
+	cfg.HH.AppContact = "dev@example.com"
+	cfg.LLM.DeepSeek.APIKey = "deepseek-key"
+	cfg.LLM.OpenAI.APIKey = "openai-key"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		change func(*Config)
+		want   string // empty for a valid config
+	}{
+		"valid": {
+			change: func(*Config) {},
+		},
+		"missing client id": {
+			change: func(c *Config) { c.HH.ClientID = "" },
+			want:   "HH_CLIENT_ID (hh.client_id) is required",
+		},
+		"no llm provider": {
+			change: func(c *Config) { c.LLM.DeepSeek.APIKey, c.LLM.OpenAI.APIKey = "", "" },
+			want:   "configure at least one LLM provider",
+		},
+		"unknown default provider": {
+			change: func(c *Config) { c.LLM.Provider = "anthropic" },
+			want:   `LLM_PROVIDER (llm.provider) must be one of the configured providers [deepseek openai], got "anthropic"`,
+		},
+		"provider timeout over write timeout": {
+			change: func(c *Config) { c.LLM.DeepSeek.Timeout = 3 * time.Minute },
+			want:   "DEEPSEEK_TIMEOUT (llm.deepseek.timeout) must be set and not exceed HTTP_WRITE_TIMEOUT (server.write_timeout) 2m0s",
+		},
+		"provider without timeout": {
+			change: func(c *Config) { c.LLM.OpenAI.Timeout = 0 },
+			want:   "OPENAI_TIMEOUT (llm.openai.timeout) must be set",
+		},
+		"no write timeout": {
+			change: func(c *Config) { c.Server.WriteTimeout, c.LLM.OpenAI.Timeout = 0, 0 },
+		},
+		"retry wait over max": {
+			change: func(c *Config) { c.HH.RetryWait = time.Minute },
+			want:   "HH_RETRY_MAX_WAIT (hh.retry_max_wait) must not be less than HH_RETRY_WAIT (hh.retry_wait)",
+		},
+		"no shutdown timeout": {
+			change: func(c *Config) { c.Server.ShutdownTimeout = 0 },
+			want:   "HTTP_SHUTDOWN_TIMEOUT (server.shutdown_timeout) must be positive",
+		},
+		"rate limit without burst": {
+			change: func(c *Config) { c.HH.RateBurst = 0 },
+			want:   "HH_RATE_BURST (hh.rate_burst) must be at least 1",
+		},
+		"fallback over write timeout": {
+			change: func(c *Config) { c.LLM.Fallback.Chain = []string{"deepseek", "openai"} },
+			want:   "LLM_FALLBACK_CHAIN (llm.fallback.chain): step timeouts add up to 3m0s, more than HTTP_WRITE_TIMEOUT (server.write_timeout) 2m0s",
+		},
+		"fallback with step timeouts": {
+			change: func(c *Config) { c.LLM.Fallback.Chain = []string{"deepseek:40s", "openai:60s"} },
+		},
+		"fallback step longer than provider": {
+			change: func(c *Config) {
+				c.LLM.DeepSeek.Timeout = 30 * time.Second
+				c.LLM.Fallback.Chain = []string{"deepseek:5m", "openai"}
+			},
+		},
+		"fallback to unknown provider": {
+			change: func(c *Config) { c.LLM.Fallback.Chain = []string{"deepseek:40s", "local"} },
+			want:   `LLM_FALLBACK_CHAIN (llm.fallback.chain): provider "local" is not configured`,
+		},
+		"fallback listed twice": {
+			change: func(c *Config) { c.LLM.Fallback.Chain = []string{"deepseek:40s", "deepseek:40s"} },
+			want:   `provider "deepseek" is listed twice`,
+		},
+		"fallback as default provider": {
+			change: func(c *Config) {
+				c.LLM.Fallback.Chain = []string{"deepseek:40s", "openai:40s"}
+				c.LLM.Provider = LLMFallbackName
+			},
+		},
+		"unknown session backend": {
+			change: func(c *Config) { c.Session.Backend = "redis" },
+			want:   `SESSION_BACKEND (session.backend) must be memory or file, got "redis"`,
+		},
+		"storage file without path": {
+			change: func(c *Config) { c.Storage.TokensFile = "" },
+			want:   "STORAGE_TOKENS_FILE (storage.tokens_file) is required for STORAGE_BACKEND=file",
+		},
+		"unknown log level": {
+			change: func(c *Config) { c.Log.Level = "loud" },
+			want:   "LOG_LEVEL (log.level)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.change(cfg)
+			err := cfg.Validate()
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+				t.Errorf("Validate: got %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := validConfig()
+	cfg.HH.ClientID = ""
+	cfg.LLM.MaxTokens = 0
+	cfg.Session.MaxAge = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, want := range []string{"HH_CLIENT_ID", "LLM_MAX_TOKENS", "SESSION_MAX_AGE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envReader переносит заданные переменные окружения в поля конфигурации,
+// накапливая ошибки разбора
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+func (r *envReader) string(dst *string, key string) {
+	if value, ok := r.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (r *envReader) int(dst *int, key string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (r *envReader) float(dst *float64, key string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (r *envReader) bool(dst *bool, key string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a boolean, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (r *envReader) duration(dst *time.Duration, key string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a duration like 30s or 5m, got %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// list разбирает значения, перечисленные через запятую
+func (r *envReader) list(dst *[]string, key string) {
+	value, ok := r.lookup(key)
+	if !ok {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// llmProvider читает <prefix>_API_URL, _API_KEY, _MODEL и _TIMEOUT
+func (r *envReader) llmProvider(dst *LLMProviderConfig, prefix string) {
+	r.string(&dst.APIURL, prefix+"_API_URL")
+	r.string(&dst.APIKey, prefix+"_API_KEY")
+	r.string(&dst.Model, prefix+"_MODEL")
+	r.duration(&dst.Timeout, prefix+"_TIMEOUT")
+}
+
+func (r *envReader) err() error {
+	return errors.Join(r.errs...)
+}
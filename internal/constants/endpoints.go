@@ -1,16 +1,20 @@
 package constants
 
 const (
-	HHURL                  = "https://hh.ru"
-	HHAPI                  = "https://api.hh.ru"
-	HHOAuth                = "https://hh.ru/oauth/token"
 	Authorize              = "/oauth/authorize"
 	Me                     = "/me"
 	ResumesMine            = "/resumes/mine"
 	Negotiations           = "/negotiations"
-	NegotiationsNidMessage = Negotiations + "/%s/messages"
+	NegotiationsNid        = Negotiations + "/%s"
+	NegotiationsNidMessage = NegotiationsNid + "/messages"
+	NegotiationsRead       = Negotiations + "/read"
 	SelectResume           = "/resumes/select"
 	Resume                 = "/resumes/%s"
 	Resumes                = "/resumes"
-	Vacancy                = "/vacancies/%s"
+	SimilarVacancies       = Resume + "/similar_vacancies"
+	Vacancies              = "/vacancies"
+	Dictionaries           = "/dictionaries"
+	Areas                  = "/areas"
+	ProfessionalRoles      = "/professional_roles"
+	Vacancy                = Vacancies + "/%s"
 )
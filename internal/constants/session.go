@@ -1,8 +1,10 @@
 package constants
 
 const (
-	AccessToken     = "access_token"
+	AuthMethod      = "auth_method"
 	CurrentResumeID = "current_resume_id"
+	HHToken         = "hh_token"
+	OAuthState      = "oauth_state"
 	UserId          = "user_id"
 	UserResume      = "user_resume"
 )
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler manages API keys of the current user
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyHandler создает новый APIKeyHandler
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// ListKeys lists API keys of the current user without their secrets
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.service.List(c.GetString(constants.UserId))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateKey issues a new API key. The key itself is returned only once.
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, errBadRequestBody)
+		return
+	}
+
+	plain, key, err := h.service.Issue(c.GetString(constants.UserId), req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": plain, "key": key})
+}
+
+// RevokeKey revokes an API key of the current user
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	err := h.service.Revoke(c.GetString(constants.UserId), c.Param("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		respondError(c, clients.NewError(clients.KindNotFound, "api_key_not_found", "api key not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,132 +1,481 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
-	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
 	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-func (ap *ApplicationHandler) GenerateCoverLetter(c *gin.Context) {
-	session := sessions.Default(c)
-	ap.service.VacancyProvider.SetAccessToken(session.Get(constants.AccessToken).(string))
+// maxDescriptionLength ограничивает вставленный текст вакансии, чтобы не
+// отправлять в LLM целые страницы
+const maxDescriptionLength = 20000
 
-	// Get current user resume
-	currentResume := session.Get(constants.CurrentResumeID)
-	if currentResume == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
-		return
+// coverLetterRequest — необязательное тело POST /api/cover-letter. Вакансия
+// задается одним из vacancy_id, vacancy_url или description; без них берется
+// первая подходящая к резюме вакансия.
+type coverLetterRequest struct {
+	VacancyID   string `json:"vacancy_id"`  // ID вакансии hh.ru
+	VacancyURL  string `json:"vacancy_url"` // Ссылка на вакансию hh.ru
+	ResumeID    string `json:"resume_id"`   // Резюме вместо выбранного в сессии
+	Description string `json:"description"` // Текст вакансии, найденной не на hh.ru
+	Title       string `json:"title"`       // Название такой вакансии
+	Company     string `json:"company"`     // Компания такой вакансии
+}
+
+// vacancyID проверяет запрос и возвращает ID вакансии hh.ru, если он задан
+func (r coverLetterRequest) vacancyID() (string, error) {
+	sources := 0
+	for _, v := range []string{r.VacancyID, r.VacancyURL, r.Description} {
+		if v != "" {
+			sources++
+		}
 	}
-	resumeID, ok := currentResume.(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
-		return
+	if sources > 1 {
+		return "", clients.NewError(clients.KindValidationFailed, "conflicting_vacancy",
+			"pass only one of vacancy_id, vacancy_url and description")
+	}
+	if r.ResumeID != "" && !isAlphanumeric(r.ResumeID) {
+		return "", clients.NewError(clients.KindValidationFailed, "invalid_resume_id", "resume_id is malformed")
 	}
-	resume, err := ap.service.VacancyProvider.GetShortResumeByID(resumeID)
+
+	switch {
+	case r.VacancyID != "":
+		if !helpers.IsVacancyID(r.VacancyID) {
+			return "", clients.NewError(clients.KindValidationFailed, "invalid_vacancy_id", "vacancy_id must be a number")
+		}
+		return r.VacancyID, nil
+	case r.VacancyURL != "":
+		id, ok := helpers.VacancyIDFromURL(r.VacancyURL)
+		if !ok {
+			return "", clients.NewError(clients.KindValidationFailed, "invalid_vacancy_url",
+				"vacancy_url must be a link like https://hh.ru/vacancy/123456")
+		}
+		return id, nil
+	case r.Description != "":
+		if strings.TrimSpace(r.Description) == "" {
+			return "", clients.NewError(clients.KindValidationFailed, "empty_description", "description is blank")
+		}
+		if utf8.RuneCountInString(r.Description) > maxDescriptionLength {
+			return "", clients.NewError(clients.KindValidationFailed, "description_too_long",
+				fmt.Sprintf("description must not exceed %d characters", maxDescriptionLength))
+		}
+	}
+	return "", nil
+}
+
+// pastedVacancy описывает вакансию не с hh.ru по тексту пользователя
+func (r coverLetterRequest) pastedVacancy() *models.VacancyShort {
+	return &models.VacancyShort{
+		Name:        strings.TrimSpace(r.Title),
+		CompanyName: strings.TrimSpace(r.Company),
+		Description: strings.TrimSpace(r.Description),
+	}
+}
+
+// isAlphanumeric защищает от подстановки в путь запроса к hh.ru
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// coverLetterJob — все, что нужно для генерации письма по запросу
+type coverLetterJob struct {
+	userID       string
+	providerName string
+	generator    services.LLMProvider
+	resume       *models.ResumeShort
+	vacancy      *models.VacancyShort
+}
+
+// prepareCoverLetter разбирает запрос на письмо, выбирает LLM-провайдера и
+// получает резюме и вакансию
+func (ap *ApplicationHandler) prepareCoverLetter(c *gin.Context) (*coverLetterJob, error) {
+	var req coverLetterRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, errBadRequestBody
+		}
+	}
+	vacancyID, err := req.vacancyID()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting resume"})
-		return
+		return nil, err
 	}
 
-	firstSimilarVacancy, err := ap.service.VacancyProvider.GetFirstShortSuitableVacancy(resumeID)
+	providerName, textGenerator, err := ap.textGenerator(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting similar vacancies"})
-		return
+		return nil, err
 	}
 
-	vacancy, err := ap.service.VacancyProvider.GetShortVacancyByID(firstSimilarVacancy.ID)
+	userID, token := userToken(c)
+	vacancyProvider := ap.service.VacancyProvider.WithToken(userID, token)
+
+	// The resume from the request takes precedence over the session one
+	resumeID := req.ResumeID
+	if resumeID == "" {
+		if resumeID, err = currentResumeID(c); err != nil {
+			return nil, err
+		}
+	}
+	resume, err := vacancyProvider.GetShortResumeByID(c.Request.Context(), resumeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting similar vacancies"})
+		return nil, err
+	}
+
+	var vacancy *models.VacancyShort
+	switch {
+	case req.Description != "":
+		vacancy = req.pastedVacancy()
+	case vacancyID != "":
+		vacancy, err = vacancyProvider.GetShortVacancyByID(c.Request.Context(), vacancyID)
+	default:
+		var firstSimilarVacancy *models.VacancyShort
+		firstSimilarVacancy, err = vacancyProvider.GetFirstShortSuitableVacancy(c.Request.Context(), resumeID)
+		if err == nil {
+			vacancy, err = vacancyProvider.GetShortVacancyByID(c.Request.Context(), firstSimilarVacancy.ID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &coverLetterJob{
+		userID:       userID,
+		providerName: providerName,
+		generator:    textGenerator,
+		resume:       resume,
+		vacancy:      vacancy,
+	}, nil
+}
+
+// saveCoverLetter сохраняет готовое письмо черновиком
+func (ap *ApplicationHandler) saveCoverLetter(
+	job *coverLetterJob, coverLetter string, usage *models.TokenUsage) (*models.CoverLetterDraft, error) {
+	draft := &models.CoverLetterDraft{
+		UserID:    job.userID,
+		VacancyID: job.vacancy.ID,
+		ResumeID:  job.resume.ID,
+		Text:      coverLetter,
+		Provider:  job.providerName,
+		Usage:     usage,
+	}
+	if err := ap.saveDraft(draft); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// GenerateCoverLetter generates a letter and keeps it as a draft. The user can
+// edit it with PUT /api/drafts/:id and send it with ApplyToVacancy.
+func (ap *ApplicationHandler) GenerateCoverLetter(c *gin.Context) {
+	job, err := ap.prepareCoverLetter(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	coverLetter, err := ap.service.TextGenerator.GenerateCoverLetter(resume, vacancy)
+	ctx, gen := ap.startGeneration(c, models.GenerationCoverLetter, job.providerName)
+	coverLetter, err := job.generator.GenerateCoverLetter(ctx, job.resume, job.vacancy)
+	usage := gen.finish(job.vacancy.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generating cover letter"})
+		respondError(c, err)
 		return
 	}
+	job.providerName = gen.providerName()
 
 	c.Set("cover_letter", coverLetter)
 
-	c.JSON(http.StatusOK, gin.H{"cover_letter": coverLetter, "vacancy": vacancy.ID, "resume": resume.ID})
+	draft, err := ap.saveCoverLetter(job, coverLetter, usage)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cover_letter": coverLetter,
+		"draft_id":     draft.ID,
+		"vacancy":      job.vacancy.ID,
+		"resume":       job.resume.ID,
+		"provider":     job.providerName,
+		"usage":        usage,
+	})
+}
+
+// applyRequest — необязательное тело POST /api/vacancies/apply/:vacancy_id.
+// Ответов на анкету работодателя в нем нет: POST /negotiations в API hh.ru
+// принимает только resume_id, vacancy_id и message, поэтому на вакансию с
+// обязательной анкетой можно откликнуться только на hh.ru.
+type applyRequest struct {
+	CoverLetter string `json:"cover_letter"` // Письмо пользователя, отправляется как есть
+	DraftID     string `json:"draft_id"`     // Черновик из POST /api/cover-letter, возможно отредактированный
+	ForceLetter bool   `json:"force_letter"` // Сгенерировать письмо, даже если вакансия его не требует
+}
+
+// validate проверяет, что письмо задано не более чем одним способом
+func (r applyRequest) validate() error {
+	if r.CoverLetter != "" && r.DraftID != "" {
+		return clients.NewError(clients.KindValidationFailed, "conflicting_cover_letter",
+			"pass either cover_letter or draft_id, not both")
+	}
+	if r.CoverLetter != "" && strings.TrimSpace(r.CoverLetter) == "" {
+		return clients.NewError(clients.KindValidationFailed, "empty_cover_letter", "cover_letter is blank")
+	}
+	return nil
 }
 
+// ApplyToVacancy applies to the vacancy with the current resume. The letter is
+// taken from the request (cover_letter or draft_id); without one it is
+// generated when the vacancy requires a letter or force_letter is set.
 func (ap *ApplicationHandler) ApplyToVacancy(c *gin.Context) {
 	var (
 		err         error
 		coverLetter string
+		usage       *models.TokenUsage
 		vacancy     *models.VacancyShort
-		session     = sessions.Default(c)
+		req         applyRequest
 	)
 
-	// Set access token from session
-	ap.service.VacancyProvider.SetAccessToken(session.Get(constants.AccessToken).(string))
+	if c.Request.ContentLength != 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			respondError(c, errBadRequestBody)
+			return
+		}
+	}
+	if err = req.validate(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Act on behalf of the current user
+	userID, token := userToken(c)
+	vacancyProvider := ap.service.VacancyProvider.WithToken(userID, token)
 
 	// Get vacancy by ID from job portal
 	vacancyID := c.Param("vacancy_id")
 	if vacancyID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "vacancy ID is required"})
+		respondError(c, errVacancyIDRequired)
 		return
 	}
-	vacancy, err = ap.service.VacancyProvider.GetShortVacancyByID(vacancyID)
+	vacancy, err = vacancyProvider.GetShortVacancyByID(c.Request.Context(), vacancyID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting vacancy"})
+		respondError(c, err)
 		return
 	}
 	if vacancy == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "vacancy not found"})
+		respondError(c, clients.NewError(clients.KindNotFound, "vacancy_not_found", "vacancy not found"))
 		return
 	}
-	if vacancy.Test.Required {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "vacancy requires a test, cannot apply directly"})
+	if vacancy.Test != nil && vacancy.Test.Required {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "test_required",
+			"vacancy requires a questionnaire, which the hh.ru API does not accept: apply on hh.ru; answers can be drafted with /api/vacancies/"+
+				vacancyID+"/test/answers"))
 		return
 	}
 
 	// Get current user resume from session
-	currentResume := session.Get(constants.CurrentResumeID)
-	if currentResume == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
-		return
-	}
-	resumeID, ok := currentResume.(string)
-	if !ok || resumeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "resume ID not found in session"})
+	resumeID, err := currentResumeID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Generate cover letter if required
-	if vacancy.ResponseLetterRequired {
-		// Get resume by ID from job portal
-		resume, err := ap.service.VacancyProvider.GetShortResumeByID(resumeID)
+	switch {
+	case req.CoverLetter != "":
+		coverLetter = req.CoverLetter
+	case req.DraftID != "":
+		draft, err := ap.loadDraft(userID, req.DraftID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error getting resume": err.Error()})
+			respondError(c, err)
+			return
+		}
+		if draft.VacancyID != "" && draft.VacancyID != vacancyID {
+			respondError(c, clients.NewError(clients.KindValidationFailed, "draft_vacancy_mismatch",
+				"the draft was written for vacancy "+draft.VacancyID))
+			return
+		}
+		coverLetter = draft.Text
+	case vacancy.ResponseLetterRequired || req.ForceLetter:
+		// Generate cover letter if required or asked for; get resume by ID from job portal
+		resume, err := vacancyProvider.GetShortResumeByID(c.Request.Context(), resumeID)
+		if err != nil {
+			respondError(c, err)
 			return
 		}
 		if resume == nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "resume not found"})
+			respondError(c, clients.NewError(clients.KindNotFound, "resume_not_found", "resume not found"))
 			return
 		}
 
-		// Generate cover letter using LLM service
-		coverLetter, err = ap.service.TextGenerator.GenerateCoverLetter(resume, vacancy)
+		// Generate cover letter using the selected LLM provider
+		providerName, textGenerator, err := ap.textGenerator(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error generating cover letter": err.Error()})
+			respondError(c, err)
 			return
 		}
+		ctx, gen := ap.startGeneration(c, models.GenerationCoverLetter, providerName)
+		coverLetter, err = textGenerator.GenerateCoverLetter(ctx, resume, vacancy)
+		usage = gen.finish(vacancyID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	err = vacancyProvider.ApplyToVacancy(c.Request.Context(), resumeID, vacancyID, coverLetter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Черновик отправлен, повторно он не понадобится
+	if req.DraftID != "" {
+		_ = ap.drafts.Delete(userID, req.DraftID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "successfully applied to vacancy",
+		"vacancy_id":   vacancyID,
+		"resume_id":    resumeID,
+		"cover_letter": coverLetter,
+		"usage":        usage,
+	})
+}
+
+// DraftReply suggests a reply to the employer's messages in the negotiation.
+// The draft is not sent: the user edits it and posts it to /messages.
+func (ap *ApplicationHandler) DraftReply(c *gin.Context) {
+	providerName, textGenerator, err := ap.textGenerator(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	vacancyProvider := ap.service.VacancyProvider.WithToken(userToken(c))
+	nid := c.Param("nid")
+
+	negotiation, err := vacancyProvider.GetNegotiation(c.Request.Context(), nid)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if negotiation.Resume == nil || negotiation.Resume.ID == "" {
+		respondError(c, clients.NewError(clients.KindNotFound, "resume_not_found", "negotiation has no resume"))
+		return
+	}
+
+	thread, err := vacancyProvider.GetNegotiationThread(c.Request.Context(), nid)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !slices.ContainsFunc(thread, func(m models.NegotiationMessage) bool {
+		return m.Author.ParticipantType == models.ParticipantEmployer
+	}) {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "no_employer_messages",
+			"the employer has not written anything to reply to"))
+		return
+	}
+
+	resume, err := vacancyProvider.GetShortResumeByID(c.Request.Context(), negotiation.Resume.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	vacancy, err := vacancyProvider.GetShortVacancyByID(c.Request.Context(), negotiation.Vacancy.ID)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	err = ap.service.VacancyProvider.ApplyToVacancy(resumeID, vacancyID, coverLetter)
+	ctx, gen := ap.startGeneration(c, models.GenerationReplyDraft, providerName)
+	draft, err := textGenerator.DraftReply(ctx, thread, resume, vacancy)
+	usage := gen.finish(vacancy.ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error applying to vacancy": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "successfully applied to vacancy",
-		"vacancy_id": vacancyID,
-		"resume_id":  resumeID,
+		"draft":          draft,
+		"negotiation_id": nid,
+		"vacancy":        vacancy.ID,
+		"resume":         resume.ID,
+		"provider":       gen.providerName(),
+		"usage":          usage,
 	})
 }
+
+// GetVacancyTest returns the employer's questionnaire of the vacancy
+func (ap *ApplicationHandler) GetVacancyTest(c *gin.Context) {
+	vacancyProvider := ap.service.VacancyProvider.WithToken(userToken(c))
+
+	test, err := vacancyProvider.GetVacancyTest(c.Request.Context(), c.Param("vacancy_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+// ProposeTestAnswers asks the LLM to answer the questionnaire from the current
+// resume. The answers are only a proposal: the user reviews them and submits
+// them on hh.ru, since the hh.ru API does not accept questionnaire answers.
+func (ap *ApplicationHandler) ProposeTestAnswers(c *gin.Context) {
+	providerName, textGenerator, err := ap.textGenerator(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	vacancyProvider := ap.service.VacancyProvider.WithToken(userToken(c))
+	vacancyID := c.Param("vacancy_id")
+
+	resumeID, err := currentResumeID(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	test, err := vacancyProvider.GetVacancyTest(c.Request.Context(), vacancyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resume, err := vacancyProvider.GetShortResumeByID(c.Request.Context(), resumeID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	vacancy, err := vacancyProvider.GetShortVacancyByID(c.Request.Context(), vacancyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx, gen := ap.startGeneration(c, models.GenerationTestAnswers, providerName)
+	answers, err := textGenerator.AnswerTest(ctx, test, resume, vacancy)
+	usage := gen.finish(vacancyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"test": test, "answers": answers, "vacancy": vacancyID, "resume": resumeID,
+		"provider": gen.providerName(), "usage": usage})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Общие ошибки запросов, на которые отвечают сразу несколько обработчиков
+var (
+	errBadRequestBody    = clients.NewError(clients.KindValidationFailed, "invalid_request_body", "failed parsing request body")
+	errVacancyIDRequired = clients.NewError(clients.KindValidationFailed, "vacancy_id_required", "vacancy ID is required")
+)
+
+// userToken returns the user ID and hh.ru token set by middleware.AuthMiddleware
+func userToken(c *gin.Context) (string, *models.HHToken) {
+	token, _ := c.MustGet(constants.HHToken).(*models.HHToken)
+	return c.GetString(constants.UserId), token
+}
+
+// respondError передает ошибку в middleware.ErrorHandler, который выберет
+// HTTP-статус и сформирует ответ
+func respondError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// currentResumeID возвращает ID резюме, выбранного через POST /api/resumes/current
+func currentResumeID(c *gin.Context) (string, error) {
+	resumeID, ok := sessions.Default(c).Get(constants.CurrentResumeID).(string)
+	if !ok || resumeID == "" {
+		return "", clients.NewError(clients.KindValidationFailed, "resume_not_selected",
+			"select a resume with POST /api/resumes/current first")
+	}
+	return resumeID, nil
+}
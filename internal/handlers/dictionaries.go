@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errDictionariesUnavailable = clients.NewError(clients.KindUpstreamUnavailable,
+	"dictionaries_unavailable", "hh.ru dictionaries are not loaded yet")
+
+// GetDictionaries returns the cached hh.ru dictionaries, areas and professional roles
+func (h *HHHandler) GetDictionaries(c *gin.Context) {
+	if h.dictionaries == nil || !h.dictionaries.Loaded() {
+		respondError(c, errDictionariesUnavailable)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.dictionaries.Snapshot())
+}
+
+// ResolveArea finds the hh.ru area ID of a city: GET /api/areas/resolve?name=Казань
+func (h *HHHandler) ResolveArea(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "name_required", "area name is required"))
+		return
+	}
+	if h.dictionaries == nil {
+		respondError(c, errDictionariesUnavailable)
+		return
+	}
+
+	area, err := h.dictionaries.ResolveArea(name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, area)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errDraftNotFound = clients.NewError(clients.KindNotFound, "draft_not_found", "cover letter draft not found")
+
+// updateDraftRequest — тело PUT /api/drafts/:id
+type updateDraftRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// saveDraft сохраняет сгенерированное письмо, чтобы его можно было
+// отредактировать и отправить через ApplyToVacancy. ID и время заполняются здесь.
+func (ap *ApplicationHandler) saveDraft(draft *models.CoverLetterDraft) error {
+	id, err := helpers.RandomToken(9)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	draft.ID = id
+	draft.CreatedAt = now
+	draft.UpdatedAt = now
+	return ap.drafts.Save(draft)
+}
+
+// loadDraft возвращает черновик текущего пользователя
+func (ap *ApplicationHandler) loadDraft(userID, id string) (*models.CoverLetterDraft, error) {
+	draft, err := ap.drafts.Get(userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, errDraftNotFound
+	}
+	return draft, err
+}
+
+// GetDraft returns a previously generated cover letter
+func (ap *ApplicationHandler) GetDraft(c *gin.Context) {
+	userID, _ := userToken(c)
+
+	draft, err := ap.loadDraft(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// UpdateDraft replaces the text of a draft with the user's edited version
+func (ap *ApplicationHandler) UpdateDraft(c *gin.Context) {
+	userID, _ := userToken(c)
+
+	var req updateDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Text) == "" {
+		respondError(c, errBadRequestBody)
+		return
+	}
+
+	draft, err := ap.loadDraft(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	draft.Text = req.Text
+	draft.Edited = true
+	draft.UpdatedAt = time.Now()
+	if err = ap.drafts.Save(draft); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// DeleteDraft removes a draft the user no longer needs
+func (ap *ApplicationHandler) DeleteDraft(c *gin.Context) {
+	userID, _ := userToken(c)
+
+	err := ap.drafts.Delete(userID, c.Param("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		respondError(c, errDraftNotFound)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,1124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/middleware"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+	"github.com/rustamnr/cover-letter-generator/internal/sessionstore"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+)
+
+// fakeHH imitates the hh.ru endpoints used by the handlers. Vacancies are named
+// after the token that requested them, vacancies quiz-required and quiz-optional
+// have a questionnaire, and negotiations are only accepted when the resume
+// belongs to the token's owner.
+type fakeHH struct {
+	server     *httptest.Server
+	mismatches atomic.Int64
+	letters    sync.Map // access token -> last cover letter
+}
+
+func newFakeHH(t *testing.T) *fakeHH {
+	f := &fakeHH{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		vacancy := map[string]any{
+			"id":   r.PathValue("id"),
+			"name": bearer(r),
+		}
+		if required, ok := strings.CutPrefix(r.PathValue("id"), "quiz-"); ok {
+			vacancy["test"] = map[string]any{
+				"required":  required == "required",
+				"questions": []any{map[string]any{"id": "q1", "type": "text", "text": "Почему мы?", "required": true}},
+			}
+		}
+		writeJSON(w, http.StatusOK, vacancy)
+	})
+	mux.HandleFunc("GET /resumes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"id": r.PathValue("id")})
+	})
+	mux.HandleFunc("POST /negotiations", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("resume_id") != "resume-"+bearer(r) {
+			f.mismatches.Add(1)
+			writeJSON(w, http.StatusForbidden, map[string]any{"errors": []any{}})
+			return
+		}
+		f.letters.Store(bearer(r), r.FormValue("message"))
+		w.WriteHeader(http.StatusCreated)
+	})
+	// OAuth: every code is exchanged for alice's token
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "alice", "refresh_token": "refresh", "expires_in": 3600})
+	})
+	mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"id": bearer(r)})
+	})
+	mux.HandleFunc("GET /negotiations/{nid}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":      r.PathValue("nid"),
+			"vacancy": map[string]any{"id": "42"},
+			"resume":  map[string]any{"id": "resume-" + bearer(r)},
+		})
+	})
+	// The thread has threadMessages messages, oldest first, and the employer wrote the last one
+	mux.HandleFunc("GET /negotiations/{nid}/messages", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		items := []any{}
+		for i := page * perPage; i < min((page+1)*perPage, threadMessages); i++ {
+			items = append(items, map[string]any{
+				"id":     fmt.Sprintf("m%d", i),
+				"text":   "message",
+				"author": map[string]any{"participant_type": "employer"},
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"found": threadMessages, "items": items, "page": page, "per_page": perPage,
+			"pages": (threadMessages + perPage - 1) / perPage,
+		})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// threadMessages — length of every negotiation thread in fakeHH, one more than a page of 100
+const threadMessages = 101
+
+func bearer(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// fakeLLM signs every text with its name, so tests can see which provider wrote it
+type fakeLLM struct{ name string }
+
+func (f fakeLLM) GenerateCoverLetter(context.Context, *models.ResumeShort, *models.VacancyShort) (string, error) {
+	return "letter from " + f.name, nil
+}
+
+func (f fakeLLM) DraftReply(context.Context, []models.NegotiationMessage, *models.ResumeShort, *models.VacancyShort) (string, error) {
+	return "reply from " + f.name, nil
+}
+
+func (f fakeLLM) AnswerTest(context.Context, *models.Test, *models.ResumeShort, *models.VacancyShort) ([]models.TestAnswer, error) {
+	return nil, nil
+}
+
+// threadLLM replies with the range of messages it was given
+type threadLLM struct{ fakeLLM }
+
+func (threadLLM) DraftReply(_ context.Context, thread []models.NegotiationMessage, _ *models.ResumeShort, _ *models.VacancyShort) (string, error) {
+	return fmt.Sprintf("%d messages, %s..%s", len(thread), thread[0].ID, thread[len(thread)-1].ID), nil
+}
+
+// downLLM fails every call and counts the attempts
+type downLLM struct {
+	fakeLLM
+	calls *atomic.Int32
+}
+
+func (d downLLM) GenerateCoverLetter(context.Context, *models.ResumeShort, *models.VacancyShort) (string, error) {
+	d.calls.Add(1)
+	return "", clients.NewError(clients.KindUpstreamUnavailable, "", "provider is down")
+}
+
+// rejectingLLM answers every request with a client error
+type rejectingLLM struct {
+	fakeLLM
+	calls *atomic.Int32
+}
+
+func (r rejectingLLM) GenerateCoverLetter(context.Context, *models.ResumeShort, *models.VacancyShort) (string, error) {
+	r.calls.Add(1)
+	return "", &clients.Error{
+		Kind:       clients.KindUpstreamUnavailable,
+		Message:    "prompt is malformed",
+		Service:    r.name,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// slowLLM streams its letter in pieces with a pause before each
+type slowLLM struct {
+	fakeLLM
+	pause time.Duration
+}
+
+func (s slowLLM) StreamCoverLetter(
+	ctx context.Context, _ *models.ResumeShort, _ *models.VacancyShort, onDelta func(string) error) (string, error) {
+	for _, piece := range []string{"letter ", "from ", s.name} {
+		select {
+		case <-time.After(s.pause):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if err := onDelta(piece); err != nil {
+			return "", err
+		}
+	}
+	return "letter from " + s.name, nil
+}
+
+func newFakeLLMs(names ...string) *services.LLMRegistry {
+	registry := services.NewLLMRegistry()
+	for _, name := range names {
+		registry.Register(name, fakeLLM{name: name})
+	}
+	return registry
+}
+
+type testApp struct {
+	router  *gin.Engine
+	tokens  storage.TokenStore
+	apiKeys *services.APIKeyService
+	drafts  storage.DraftStore
+}
+
+// login stores the user's hh.ru token and returns an API key of the user
+func (a *testApp) login(t *testing.T, userID, accessToken string) string {
+	if err := a.tokens.Save(userID, &models.HHToken{AccessToken: accessToken}); err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := a.apiKeys.Issue(userID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// testAppOptions are the parts of the fixture a test may replace; by default
+// everything is in memory and the LLMs are fakeLLMs "first" and "second"
+type testAppOptions struct {
+	llms        *services.LLMRegistry
+	usageStore  storage.UsageStore
+	usageConfig config.LLMUsageConfig
+	tokens      storage.TokenStore
+	apiKeys     storage.APIKeyStore
+}
+
+type testAppOption func(*testAppOptions)
+
+func withLLMs(llms *services.LLMRegistry) testAppOption {
+	return func(o *testAppOptions) { o.llms = llms }
+}
+
+func withUsage(store storage.UsageStore, cfg config.LLMUsageConfig) testAppOption {
+	return func(o *testAppOptions) { o.usageStore, o.usageConfig = store, cfg }
+}
+
+func withTokens(tokens storage.TokenStore) testAppOption {
+	return func(o *testAppOptions) { o.tokens = tokens }
+}
+
+func withAPIKeys(apiKeys storage.APIKeyStore) testAppOption {
+	return func(o *testAppOptions) { o.apiKeys = apiKeys }
+}
+
+func newTestApp(t *testing.T, hhAPI string, opts ...testAppOption) *testApp {
+	o := testAppOptions{
+		llms:       newFakeLLMs("first", "second"),
+		usageStore: storage.NewMemoryUsageStore(),
+		tokens:     storage.NewMemoryTokenStore(),
+		apiKeys:    storage.NewMemoryAPIKeyStore(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	gin.SetMode(gin.TestMode)
+	usage, err := services.NewUsageService(o.usageStore, o.usageConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := o.tokens
+	apiKeys := services.NewAPIKeyService(o.apiKeys)
+	hhClient := clients.NewHHClient(config.HHConfig{APIURL: hhAPI}, tokens)
+	applicationService := services.NewApplicationService(services.NewHHProvider(hhClient), o.llms)
+
+	hhHandler := NewHHHandler(hhClient, tokens, nil)
+	drafts := storage.NewMemoryDraftStore()
+	applicationHandler := NewApplicationHandler(applicationService, drafts, storage.NewMemorySettingsStore(), usage)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("test"))))
+	// The current resume normally comes from the session; take it from a header here.
+	router.Use(func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set(constants.CurrentResumeID, c.GetHeader("X-Resume-ID"))
+		// A browser login: the user comes from the session cookie
+		if userID := c.GetHeader("X-Session-User"); userID != "" {
+			session.Set(constants.UserId, userID)
+		}
+		c.Next()
+	})
+
+	api := router.Group("/api", middleware.AuthMiddleware(tokens, apiKeys))
+	api.GET("/vacancies/:vacancy_id", hhHandler.GetVacancyByID)
+	api.POST("/vacancies/apply/:vacancy_id", applicationHandler.ApplyToVacancy)
+	api.POST("/cover-letter", applicationHandler.GenerateCoverLetter)
+	api.POST("/cover-letter/stream", applicationHandler.StreamCoverLetter)
+	api.POST("/negotiations/:nid/reply-draft", applicationHandler.DraftReply)
+	api.PUT("/settings", applicationHandler.UpdateSettings)
+	api.GET("/usage", applicationHandler.GetUsage)
+	api.POST("/tokens", middleware.SessionOnly(), NewAPIKeyHandler(apiKeys).CreateKey)
+	api.GET("/drafts/:id", applicationHandler.GetDraft)
+	api.PUT("/drafts/:id", applicationHandler.UpdateDraft)
+	api.DELETE("/drafts/:id", applicationHandler.DeleteDraft)
+	return &testApp{router: router, tokens: tokens, apiKeys: apiKeys, drafts: drafts}
+}
+
+func TestConcurrentUsersDoNotShareTokens(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+
+	const users = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, users*2)
+
+	for i := 0; i < users; i++ {
+		token := fmt.Sprintf("token-%d", i)
+		apiKey := app.login(t, fmt.Sprintf("user-%d", i), token)
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/api/vacancies/42", nil)
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			rec := httptest.NewRecorder()
+			app.router.ServeHTTP(rec, req)
+
+			var vacancy struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &vacancy); err != nil || vacancy.Name != token {
+				errs <- fmt.Errorf("%s: got vacancy %q (status %d)", token, rec.Body.String(), rec.Code)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/api/vacancies/apply/42", nil)
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+			req.Header.Set("X-Resume-ID", "resume-"+token)
+			rec := httptest.NewRecorder()
+			app.router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				errs <- fmt.Errorf("%s: apply failed with %d: %s", token, rec.Code, rec.Body.String())
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := hh.mismatches.Load(); n != 0 {
+		t.Errorf("hh.ru received %d negotiations with another user's token", n)
+	}
+}
+
+func (a *testApp) do(t *testing.T, method, target, apiKey, resumeID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if resumeID != "" {
+		req.Header.Set("X-Resume-ID", resumeID)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHHTokensSurviveRestart(t *testing.T) {
+	hh := newFakeHH(t)
+	tokensFile := filepath.Join(t.TempDir(), "tokens.json")
+	tokens, err := storage.NewFileTokenStore(tokensFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestApp(t, hh.server.URL, withTokens(tokens)).login(t, "alice", "alice")
+
+	// The session cookie outlives the process; the hh.ru token has to as well
+	tokens, err = storage.NewFileTokenStore(tokensFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp(t, hh.server.URL, withTokens(tokens))
+	req := httptest.NewRequest(http.MethodGet, "/api/vacancies/42", nil)
+	req.Header.Set("X-Session-User", "alice")
+	rec := httptest.NewRecorder()
+	app.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"alice"`) {
+		t.Errorf("session after restart: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if info, err := os.Stat(tokensFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("tokens file: %v, %v; want mode 0600", info, err)
+	}
+}
+
+func TestAPIKeysSurviveRestart(t *testing.T) {
+	hh := newFakeHH(t)
+	keysFile := filepath.Join(t.TempDir(), "api_keys.json")
+	openKeys := func() *storage.FileAPIKeyStore {
+		keys, err := storage.NewFileAPIKeyStore(keysFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+	tokens := storage.NewMemoryTokenStore()
+	apiKey := newTestApp(t, hh.server.URL, withTokens(tokens), withAPIKeys(openKeys())).login(t, "alice", "alice")
+
+	app := newTestApp(t, hh.server.URL, withTokens(tokens), withAPIKeys(openKeys()))
+	if rec := app.do(t, http.MethodGet, "/api/vacancies/42", apiKey, "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("API key after restart: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	data, err := os.ReadFile(keysFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), apiKey) {
+		t.Error("the API key itself was written to disk, want only its hash")
+	}
+}
+
+func TestAPIKeyCannotIssueKeys(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	apiKey := app.login(t, "alice", "alice")
+
+	issue := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(`{"name":"cli"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		app.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := issue("Authorization", "Bearer "+apiKey); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "session_required") {
+		t.Errorf("issue with an API key: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := issue("X-Session-User", "alice"); rec.Code != http.StatusCreated {
+		t.Errorf("issue from the browser session: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoginRotatesSessionID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hh := newFakeHH(t)
+	store, err := sessionstore.NewStore(sessionstore.NewMemoryBackend(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hhClient := clients.NewHHClient(config.HHConfig{
+		URL: hh.server.URL, APIURL: hh.server.URL, OAuthURL: hh.server.URL + "/oauth/token",
+	}, nil)
+	hhHandler := NewHHHandler(hhClient, storage.NewMemoryTokenStore(), nil)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.Use(sessions.Sessions("session", store))
+	router.GET("/auth", hhHandler.AuthHandler)
+	router.GET("/auth/callback", hhHandler.CallbackHandler)
+
+	// get sends the request with the cookie and returns the last cookie set in response
+	get := func(target string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		cookies := rec.Result().Cookies()
+		if len(cookies) == 0 {
+			t.Fatalf("GET %s: no session cookie, status %d", target, rec.Code)
+		}
+		return rec, cookies[len(cookies)-1]
+	}
+	// sessionID returns the ID the store accepts from the cookie, "" if none
+	sessionID := func(cookie *http.Cookie) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		session, err := store.New(req, "session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session.ID
+	}
+
+	// The attacker starts a login and plants the cookie in the victim's browser
+	rec, planted := get("/auth", nil)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plantedID := sessionID(planted)
+	if plantedID == "" {
+		t.Fatal("the cookie from /auth has no session")
+	}
+
+	rec, loggedIn := get("/auth/callback?code=c&state="+location.Query().Get("state"), planted)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if id := sessionID(loggedIn); id == "" || id == plantedID {
+		t.Errorf("session ID after login = %q, want a fresh one", id)
+	}
+	if id := sessionID(planted); id != "" {
+		t.Errorf("the pre-login ID is still accepted as %q", id)
+	}
+
+	// A cookie with an ID the store does not know gets a new ID instead of adopting it
+	if _, cookie := get("/auth", planted); sessionID(cookie) == "" || sessionID(planted) != "" {
+		t.Error("an unknown session ID from the cookie was adopted")
+	}
+}
+
+func TestApplyWithEditedDraft(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	alice := app.login(t, "alice", "alice")
+	bob := app.login(t, "bob", "bob")
+
+	draft := &models.CoverLetterDraft{ID: "d1", UserID: "alice", VacancyID: "42", Text: "generated", UpdatedAt: time.Now()}
+	if err := app.drafts.Save(draft); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other users can neither read nor send the draft
+	if rec := app.do(t, http.MethodGet, "/api/drafts/d1", bob, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("bob reads alice's draft: status %d, want 404", rec.Code)
+	}
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/42", bob, "resume-bob", `{"draft_id":"d1"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("bob applies with alice's draft: status %d, want 404", rec.Code)
+	}
+
+	if rec := app.do(t, http.MethodPut, "/api/drafts/d1", alice, "", `{"text":"edited by hand"}`); rec.Code != http.StatusOK {
+		t.Fatalf("update draft: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/7", alice, "resume-alice", `{"draft_id":"d1"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("draft for another vacancy: status %d, want 400", rec.Code)
+	}
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/42", alice, "resume-alice", `{"draft_id":"d1","cover_letter":"x"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("both draft_id and cover_letter: status %d, want 400", rec.Code)
+	}
+
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/42", alice, "resume-alice", `{"draft_id":"d1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("apply with draft: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if letter, _ := hh.letters.Load("alice"); letter != "edited by hand" {
+		t.Errorf("hh.ru received letter %q, want the edited draft", letter)
+	}
+
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/43", alice, "resume-alice", `{"cover_letter":"my own words"}`); rec.Code != http.StatusOK {
+		t.Fatalf("apply with own letter: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if letter, _ := hh.letters.Load("alice"); letter != "my own words" {
+		t.Errorf("hh.ru received letter %q, want the user's letter", letter)
+	}
+}
+
+func TestDraftLimits(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	alice := app.login(t, "alice", "alice")
+	bob := app.login(t, "bob", "bob")
+
+	now := time.Now()
+	for i := range storage.MaxDraftsPerUser + 1 {
+		draft := &models.CoverLetterDraft{ID: fmt.Sprintf("d%d", i), UserID: "alice", Text: "letter",
+			UpdatedAt: now.Add(time.Duration(i) * time.Minute)}
+		if err := app.drafts.Save(draft); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rec := app.do(t, http.MethodGet, "/api/drafts/d0", alice, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("oldest draft over the limit: status %d, want 404", rec.Code)
+	}
+	if rec := app.do(t, http.MethodGet, "/api/drafts/d1", alice, "", ""); rec.Code != http.StatusOK {
+		t.Errorf("draft within the limit: status %d, want 200", rec.Code)
+	}
+
+	stale := &models.CoverLetterDraft{ID: "stale", UserID: "alice", Text: "letter", UpdatedAt: now.Add(-storage.DraftTTL - time.Hour)}
+	if err := app.drafts.Save(stale); err != nil {
+		t.Fatal(err)
+	}
+	if rec := app.do(t, http.MethodGet, "/api/drafts/stale", alice, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expired draft: status %d, want 404", rec.Code)
+	}
+
+	if rec := app.do(t, http.MethodDelete, "/api/drafts/d50", bob, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("bob deletes alice's draft: status %d, want 404", rec.Code)
+	}
+	if rec := app.do(t, http.MethodDelete, "/api/drafts/d50", alice, "", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete draft: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := app.do(t, http.MethodGet, "/api/drafts/d50", alice, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted draft: status %d, want 404", rec.Code)
+	}
+}
+
+func TestApplyToVacancyWithTest(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	apiKey := app.login(t, "alice", "alice")
+
+	rec := app.do(t, http.MethodPost, "/api/vacancies/apply/quiz-required", apiKey, "resume-alice", `{"cover_letter":"hi"}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "test_required") {
+		t.Errorf("required questionnaire: status %d, want 400 test_required: %s", rec.Code, rec.Body.String())
+	}
+	if _, sent := hh.letters.Load("alice"); sent {
+		t.Error("hh.ru received a negotiation for a vacancy with a required questionnaire")
+	}
+
+	if rec := app.do(t, http.MethodPost, "/api/vacancies/apply/quiz-optional", apiKey, "resume-alice", `{"cover_letter":"hi"}`); rec.Code != http.StatusOK {
+		t.Errorf("optional questionnaire: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReplyDraftSeesRecentMessages(t *testing.T) {
+	hh := newFakeHH(t)
+	llms := services.NewLLMRegistry()
+	llms.Register("thread", threadLLM{fakeLLM{name: "thread"}})
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	apiKey := app.login(t, "alice", "alice")
+
+	rec := app.do(t, http.MethodPost, "/api/negotiations/n1/reply-draft", apiKey, "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reply draft: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Draft string `json:"draft"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	// 101 messages span two pages of 100; the model gets the 100 most recent
+	if body.Draft != "100 messages, m1..m100" {
+		t.Errorf("the model saw %q, want 100 messages, m1..m100", body.Draft)
+	}
+}
+
+func TestCoverLetterVacancySources(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	apiKey := app.login(t, "alice", "alice")
+
+	// fakeHH echoes the IDs from the request path, so the response shows which
+	// vacancy and resume were fetched
+	for name, tc := range map[string]struct {
+		body, vacancy, resume string
+	}{
+		"vacancy id":      {`{"vacancy_id":"42"}`, "42", "resume-alice"},
+		"regional url":    {`{"vacancy_url":"https://spb.hh.ru/vacancy/43?from=search"}`, "43", "resume-alice"},
+		"api url":         {`{"vacancy_url":"https://api.hh.ru/vacancies/44"}`, "44", "resume-alice"},
+		"resume override": {`{"vacancy_id":"42","resume_id":"other"}`, "42", "other"},
+		"description":     {`{"description":"Go developer"}`, "", "resume-alice"},
+	} {
+		rec := app.do(t, http.MethodPost, "/api/cover-letter", apiKey, "resume-alice", tc.body)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", name, rec.Code, rec.Body.String())
+			continue
+		}
+		var body struct {
+			Vacancy string `json:"vacancy"`
+			Resume  string `json:"resume"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Vacancy != tc.vacancy || body.Resume != tc.resume {
+			t.Errorf("%s: vacancy %q, resume %q, want %q, %q", name, body.Vacancy, body.Resume, tc.vacancy, tc.resume)
+		}
+	}
+}
+
+func TestCoverLetterRejectsAmbiguousVacancy(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	apiKey := app.login(t, "alice", "alice")
+
+	for name, body := range map[string]string{
+		"id and url":         `{"vacancy_id":"42","vacancy_url":"https://hh.ru/vacancy/42"}`,
+		"id and description": `{"vacancy_id":"42","description":"Go developer"}`,
+		"foreign url":        `{"vacancy_url":"https://example.com/vacancy/42"}`,
+		"not a vacancy url":  `{"vacancy_url":"https://hh.ru/resume/42"}`,
+		"id with a path":     `{"vacancy_id":"42/../../me"}`,
+		"resume with a path": `{"vacancy_id":"42","resume_id":"../me"}`,
+		"blank description":  `{"description":"   "}`,
+	} {
+		rec := app.do(t, http.MethodPost, "/api/cover-letter", apiKey, "resume-alice", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCoverLetterProviderSelection(t *testing.T) {
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL)
+	alice := app.login(t, "alice", "alice")
+	bob := app.login(t, "bob", "bob")
+
+	provider := func(apiKey, query string) string {
+		t.Helper()
+		rec := app.do(t, http.MethodPost, "/api/cover-letter"+query, apiKey, "resume-1", `{"description":"Go developer"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cover letter%s: status %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var body struct {
+			CoverLetter string `json:"cover_letter"`
+			Provider    string `json:"provider"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.CoverLetter != "letter from "+body.Provider {
+			t.Errorf("letter %q was not written by the reported provider %q", body.CoverLetter, body.Provider)
+		}
+		return body.Provider
+	}
+
+	if got := provider(alice, ""); got != "first" {
+		t.Errorf("default provider = %q, want first", got)
+	}
+
+	if rec := app.do(t, http.MethodPut, "/api/settings", alice, "", `{"llm_provider":"second"}`); rec.Code != http.StatusOK {
+		t.Fatalf("select provider: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := provider(alice, ""); got != "second" {
+		t.Errorf("alice's provider = %q, want her choice second", got)
+	}
+	if got := provider(bob, ""); got != "first" {
+		t.Errorf("bob's provider = %q, alice's choice must not affect him", got)
+	}
+	if got := provider(alice, "?provider=first"); got != "first" {
+		t.Errorf("?provider=first gave %q", got)
+	}
+
+	if rec := app.do(t, http.MethodPost, "/api/cover-letter?provider=nope", alice, "resume-1", `{"description":"Go"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown provider: status %d, want 400", rec.Code)
+	}
+	if rec := app.do(t, http.MethodPut, "/api/settings", alice, "", `{"llm_provider":"nope"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("select unknown provider: status %d, want 400", rec.Code)
+	}
+}
+
+func TestCoverLetterFallback(t *testing.T) {
+	hh := newFakeHH(t)
+	down := downLLM{fakeLLM: fakeLLM{name: "down"}, calls: &atomic.Int32{}}
+	llms := newFakeLLMs("first")
+	llms.Register("down", down)
+	llms.Register("fallback", services.NewFallbackProvider([]services.FallbackStep{
+		{Name: "down", Provider: down},
+		{Name: "first", Provider: fakeLLM{name: "first"}},
+	}, 2, 50*time.Millisecond))
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	alice := app.login(t, "alice", "alice")
+
+	generate := func(provider string) *httptest.ResponseRecorder {
+		return app.do(t, http.MethodPost, "/api/cover-letter?provider="+provider, alice, "resume-1", `{"description":"Go developer"}`)
+	}
+	for i := 0; i < 3; i++ {
+		rec := generate("fallback")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d: %s", i, rec.Code, rec.Body.String())
+		}
+		var body struct {
+			CoverLetter string `json:"cover_letter"`
+			Provider    string `json:"provider"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Provider != "first" || body.CoverLetter != "letter from first" {
+			t.Errorf("request %d: provider %q wrote %q, want the answer of first", i, body.Provider, body.CoverLetter)
+		}
+	}
+	// After two failures in a row the circuit is open and down is skipped
+	if got := down.calls.Load(); got != 2 {
+		t.Errorf("down was called %d times, want 2", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if rec := generate("fallback"); rec.Code != http.StatusOK {
+		t.Fatalf("after cooldown: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := down.calls.Load(); got != 3 {
+		t.Errorf("down was called %d times, want a probe after the cooldown", got)
+	}
+
+	llms.Register("dead", services.NewFallbackProvider([]services.FallbackStep{
+		{Name: "down", Provider: down},
+	}, 2, time.Minute))
+	rec := generate("dead")
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "llm_unavailable") {
+		t.Errorf("all providers down: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCoverLetterFallbackStopsOnClientError(t *testing.T) {
+	hh := newFakeHH(t)
+	rejecting := rejectingLLM{fakeLLM: fakeLLM{name: "rejecting"}, calls: &atomic.Int32{}}
+	llms := newFakeLLMs("first")
+	llms.Register("fallback", services.NewFallbackProvider([]services.FallbackStep{
+		{Name: "rejecting", Provider: rejecting},
+		{Name: "first", Provider: fakeLLM{name: "first"}},
+	}, 2, time.Minute))
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	alice := app.login(t, "alice", "alice")
+
+	for i := 0; i < 3; i++ {
+		rec := app.do(t, http.MethodPost, "/api/cover-letter?provider=fallback", alice, "resume-1", `{"description":"Go developer"}`)
+		if rec.Code == http.StatusOK || !strings.Contains(rec.Body.String(), "prompt is malformed") {
+			t.Errorf("request %d: status %d: %s, want the error of rejecting", i, rec.Code, rec.Body.String())
+		}
+	}
+	// A rejected request neither moves on to first nor opens the circuit
+	if got := rejecting.calls.Load(); got != 3 {
+		t.Errorf("rejecting was called %d times, want 3", got)
+	}
+}
+
+func TestCoverLetterFromLocalLLM(t *testing.T) {
+	var prompt atomic.Value
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "unexpected request"}})
+			return
+		}
+		var request clients.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		prompt.Store(request.Messages[len(request.Messages)-1].Content)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": "Письмо от локальной модели"}}},
+		})
+	}))
+	t.Cleanup(llm.Close)
+
+	local := config.LocalLLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIURL: llm.URL + "/v1", Model: "llama3"},
+		ContextWindow:     8192,
+	}
+	llms := services.NewLLMRegistry()
+	llms.Register(clients.ServiceLocal, services.NewLLMService(clients.NewLocalLLMClient(local), 1024))
+
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	apiKey := app.login(t, "alice", "alice")
+
+	rec := app.do(t, http.MethodPost, "/api/cover-letter", apiKey, "resume-1", `{"description":"Go-разработчик в финтех","title":"Go developer"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		CoverLetter string `json:"cover_letter"`
+		Provider    string `json:"provider"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.CoverLetter != "Письмо от локальной модели" || body.Provider != clients.ServiceLocal {
+		t.Errorf("response = %+v", body)
+	}
+	if p, _ := prompt.Load().(string); !strings.Contains(p, "Go-разработчик в финтех") {
+		t.Errorf("the pasted vacancy did not reach the model: %q", p)
+	}
+}
+
+func TestUsageAndBudget(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": "Письмо"}}},
+			"usage": map[string]any{
+				"prompt_tokens": 1000, "completion_tokens": 500, "prompt_cache_hit_tokens": 400, "prompt_cache_miss_tokens": 600,
+			},
+		})
+	}))
+	t.Cleanup(llm.Close)
+
+	llms := services.NewLLMRegistry()
+	deepseek := clients.NewDeepSeekClient(config.LLMProviderConfig{APIURL: llm.URL, Model: "deepseek-chat"})
+	llms.Register(clients.ServiceDeepSeek, services.NewLLMService(deepseek, 1024))
+
+	hh := newFakeHH(t)
+	usageConfig := config.LLMUsageConfig{
+		Prices:        []string{"deepseek-chat=2/1/4"},
+		MonthlyBudget: 1,
+		UserBudgets:   []string{"alice=0.005"},
+		Months:        2,
+	}
+	usageFile := filepath.Join(t.TempDir(), "usage.jsonl")
+	usageStore, err := storage.NewFileUsageStore(usageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Usage from three months ago is beyond the kept months and gets pruned
+	old := &models.GenerationUsage{ID: "old", UserID: "alice", CreatedAt: time.Now().AddDate(0, -3, 0)}
+	if err := usageStore.Add(old); err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp(t, hh.server.URL, withLLMs(llms), withUsage(usageStore, usageConfig))
+	alice := app.login(t, "alice", "alice")
+	bob := app.login(t, "bob", "bob")
+
+	generate := func(apiKey string) *httptest.ResponseRecorder {
+		return app.do(t, http.MethodPost, "/api/cover-letter", apiKey, "resume-1", `{"description":"Go developer"}`)
+	}
+	// 600 prompt tokens at $2, 400 cached at $1 and 500 completion at $4 per million
+	const cost = 0.0036
+
+	rec := generate(alice)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Usage models.TokenUsage `json:"usage"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	want := models.TokenUsage{PromptTokens: 1000, CompletionTokens: 500, CacheHitTokens: 400, Cost: cost}
+	if body.Usage.PromptTokens != want.PromptTokens || body.Usage.CacheHitTokens != want.CacheHitTokens ||
+		body.Usage.CompletionTokens != want.CompletionTokens || fmt.Sprintf("%.6f", body.Usage.Cost) != fmt.Sprintf("%.6f", cost) {
+		t.Errorf("usage = %+v, want %+v", body.Usage, want)
+	}
+
+	// The budget is checked before a generation, so the second one may overshoot it
+	if rec := generate(alice); rec.Code != http.StatusOK {
+		t.Fatalf("second letter: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := generate(alice); rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "llm_budget_exceeded") {
+		t.Errorf("over budget: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := generate(bob); rec.Code != http.StatusOK {
+		t.Errorf("bob is within the common budget: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = app.do(t, http.MethodGet, "/api/usage", alice, "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("usage: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var summary models.UsageSummary
+	_ = json.Unmarshal(rec.Body.Bytes(), &summary)
+	if len(summary.Generations) != 2 || summary.Total.PromptTokens != 2000 || summary.Budget != 0.005 || !summary.Exceeded {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if g := summary.Generations[0]; g.Kind != models.GenerationCoverLetter || g.Provider != clients.ServiceDeepSeek || g.Model != "deepseek-chat" {
+		t.Errorf("generation = %+v", g)
+	}
+
+	// The spent budget survives a restart
+	usageStore, err = storage.NewFileUsageStore(usageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app = newTestApp(t, hh.server.URL, withLLMs(llms), withUsage(usageStore, usageConfig))
+	alice = app.login(t, "alice", "alice")
+	if rec := generate(alice); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("over budget after restart: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if data, _ := os.ReadFile(usageFile); strings.Contains(string(data), `"id":"old"`) {
+		t.Errorf("usage older than the kept months was not pruned: %s", data)
+	}
+
+	rec = app.do(t, http.MethodGet, "/api/usage?month=2000-01", alice, "", "")
+	_ = json.Unmarshal(rec.Body.Bytes(), &summary)
+	if rec.Code != http.StatusOK || len(summary.Generations) != 0 || summary.Total.Cost != 0 {
+		t.Errorf("another month: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStreamOutlivesWriteTimeout(t *testing.T) {
+	llms := services.NewLLMRegistry()
+	llms.Register("slow", slowLLM{fakeLLM: fakeLLM{name: "slow"}, pause: 100 * time.Millisecond})
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	apiKey := app.login(t, "alice", "alice")
+
+	server := httptest.NewUnstartedServer(app.router)
+	server.Config.WriteTimeout = 150 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/cover-letter/stream", strings.NewReader(`{"description":"Go developer"}`))
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("X-Resume-ID", "resume-1")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	events := parseSSE(string(data))
+	if len(events) == 0 || events[len(events)-1].name != "done" {
+		t.Errorf("a stream longer than the write timeout was cut: %q", data)
+	}
+}
+
+// sseEvent is one event of a text/event-stream response
+type sseEvent struct {
+	name string
+	data string
+}
+
+func parseSSE(body string) []sseEvent {
+	var events []sseEvent
+	for _, block := range strings.Split(body, "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			field, value, _ := strings.Cut(line, ":")
+			switch field {
+			case "event":
+				event.name = value
+			case "data":
+				event.data = value
+			}
+		}
+		if event.name != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// cancelOnWrite cancels the request once the response contains marker, like a
+// browser tab closed in the middle of the stream
+type cancelOnWrite struct {
+	*httptest.ResponseRecorder
+	marker string
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnWrite) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), w.marker) {
+		defer w.cancel()
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamCoverLetter(t *testing.T) {
+	var disconnected atomic.Bool
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk := func(data string) {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			w.(http.Flusher).Flush()
+		}
+		chunk(`{"choices": [{"delta": {"content": "Добрый "}}]}`)
+		switch r.URL.Query().Get("scenario") {
+		case "error":
+			chunk(`{"error": {"message": "Server overloaded", "type": "server_error"}}`)
+		case "hang":
+			select {
+			case <-r.Context().Done():
+				disconnected.Store(true)
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			chunk(`{"choices": [{"delta": {"content": "день"}, "finish_reason": "stop"}]}`)
+			chunk(`{"choices": [], "usage": {"prompt_tokens": 10, "completion_tokens": 2}}`)
+			chunk(`[DONE]`)
+		}
+	}))
+	t.Cleanup(llm.Close)
+
+	llms := services.NewLLMRegistry()
+	for _, scenario := range []string{"ok", "error", "hang"} {
+		client := clients.NewOpenAIClient(config.LLMProviderConfig{APIURL: llm.URL + "?scenario=" + scenario, APIKey: "key", Model: "gpt"})
+		llms.Register(scenario, services.NewLLMService(client, 100))
+	}
+	hh := newFakeHH(t)
+	app := newTestApp(t, hh.server.URL, withLLMs(llms))
+	apiKey := app.login(t, "alice", "alice")
+	body := `{"description":"Go developer"}`
+
+	rec := app.do(t, http.MethodPost, "/api/cover-letter/stream?provider=ok", apiKey, "resume-1", body)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q: %s", ct, rec.Body.String())
+	}
+	events := parseSSE(rec.Body.String())
+	var names []string
+	for _, e := range events {
+		names = append(names, e.name)
+	}
+	if strings.Join(names, ",") != "start,delta,delta,done" {
+		t.Fatalf("events = %v", names)
+	}
+	var done struct {
+		CoverLetter string `json:"cover_letter"`
+		DraftID     string `json:"draft_id"`
+	}
+	_ = json.Unmarshal([]byte(events[3].data), &done)
+	if done.CoverLetter != "Добрый день" || done.DraftID == "" {
+		t.Errorf("done = %s", events[3].data)
+	}
+	if draft, err := app.drafts.Get("alice", done.DraftID); err != nil || draft.Text != "Добрый день" {
+		t.Errorf("draft = %+v, %v", draft, err)
+	}
+
+	rec = app.do(t, http.MethodPost, "/api/cover-letter/stream?provider=error", apiKey, "resume-1", body)
+	events = parseSSE(rec.Body.String())
+	if last := events[len(events)-1]; last.name != "error" || !strings.Contains(last.data, "Server overloaded") {
+		t.Errorf("last event = %+v, want the upstream error", last)
+	}
+
+	// Validation errors come before the stream as ordinary JSON
+	rec = app.do(t, http.MethodPost, "/api/cover-letter/stream?provider=nope", apiKey, "resume-1", body)
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Header().Get("Content-Type"), "event-stream") {
+		t.Errorf("unknown provider: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// The browser goes away after the first piece: the upstream request is dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/cover-letter/stream?provider=hang", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("X-Resume-ID", "resume-1")
+	w := &cancelOnWrite{ResponseRecorder: httptest.NewRecorder(), marker: `"text"`, cancel: cancel}
+	app.router.ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), "event:done") || strings.Contains(w.Body.String(), "event:error") {
+		t.Errorf("stream went on after the client left: %s", w.Body.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !disconnected.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !disconnected.Load() {
+		t.Error("the LLM request was not canceled after the client disconnected")
+	}
+}
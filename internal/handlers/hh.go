@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/rustamnr/cover-letter-generator/internal/clients"
 	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
 	"github.com/rustamnr/cover-letter-generator/internal/models"
 	"github.com/rustamnr/cover-letter-generator/internal/services"
+	"github.com/rustamnr/cover-letter-generator/internal/sessionstore"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -16,76 +21,123 @@ import (
 
 // ApplicationHandler обрабатывает запросы, связанные с заявками
 type ApplicationHandler struct {
-	service *services.ApplicationService
+	service  *services.ApplicationService
+	drafts   storage.DraftStore
+	settings storage.SettingsStore
+	usage    *services.UsageService
 }
 
 // NewApplicationHandler создает новый ApplicationHandler
-func NewApplicationHandler(service *services.ApplicationService) *ApplicationHandler {
-	return &ApplicationHandler{service: service}
+func NewApplicationHandler(service *services.ApplicationService,
+	drafts storage.DraftStore, settings storage.SettingsStore, usage *services.UsageService) *ApplicationHandler {
+	return &ApplicationHandler{service: service, drafts: drafts, settings: settings, usage: usage}
 }
 
 // HHHandler handles requests related to hh.ru
 type HHHandler struct {
-	hhClient *clients.HHClient
+	hhClient     *clients.HHClient
+	tokens       storage.TokenStore
+	dictionaries *clients.Dictionaries
 }
 
-// NewHHHandler создает новый HHHandler
-func NewHHHandler(hhClient *clients.HHClient) *HHHandler {
-	return &HHHandler{hhClient: hhClient}
+// NewHHHandler создает новый HHHandler. dictionaries может быть nil, тогда
+// фильтры не проверяются по справочникам.
+func NewHHHandler(hhClient *clients.HHClient, tokens storage.TokenStore, dictionaries *clients.Dictionaries) *HHHandler {
+	return &HHHandler{hhClient: hhClient, tokens: tokens, dictionaries: dictionaries}
 }
 
-// AuthHandler redirects user to the authorization page
+// AuthHandler redirects user to the authorization page. A random state is kept
+// in the session so that CallbackHandler only accepts logins started here.
 func (h *HHHandler) AuthHandler(c *gin.Context) {
-	authURL := fmt.Sprintf("https://hh.ru/oauth/authorize?response_type=code&client_id=%s", h.hhClient.ClientID)
-	c.Redirect(http.StatusFound, authURL)
+	state, err := helpers.RandomToken(32)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to generate oauth state: %w", err))
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set(constants.OAuthState, state)
+	if err = session.Save(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, h.hhClient.AuthURL(state))
 }
 
 // CallbackHandler handles the OAuth callback
 func (h *HHHandler) CallbackHandler(c *gin.Context) {
+	session := sessions.Default(c)
+
+	// The state is single-use: drop it whatever the outcome
+	expectedState, _ := session.Get(constants.OAuthState).(string)
+	session.Delete(constants.OAuthState)
+	if err := session.Save(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		renderAuthError(c, http.StatusBadRequest, "hh.ru отклонил авторизацию: "+reason)
+		return
+	}
+
+	state := c.Query("state")
+	if expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		renderAuthError(c, http.StatusForbidden,
+			"Ссылка для входа устарела или была открыта не из этого браузера.")
+		return
+	}
+
 	code := c.Query("code")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "authorization code not found"})
+		renderAuthError(c, http.StatusBadRequest, "hh.ru не передал код авторизации.")
 		return
 	}
 
-	accessToken, err := h.hhClient.ExchangeCodeForToken(code)
+	token, err := h.hhClient.ExchangeCodeForToken(c.Request.Context(), code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("failed to exchange oauth code: %v", err)
+		renderAuthError(c, http.StatusBadGateway, "Не удалось получить токен hh.ru, попробуйте войти еще раз.")
 		return
 	}
 
-	userID, err := h.hhClient.GetUserID(accessToken)
+	userID, err := h.hhClient.GetUserID(c.Request.Context(), token.AccessToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("failed to get hh.ru user: %v", err)
+		renderAuthError(c, http.StatusBadGateway, "Не удалось получить профиль hh.ru, попробуйте войти еще раз.")
 		return
 	}
 
-	session := sessions.Default(c)
-	session.Set(constants.AccessToken, accessToken)
+	if err = h.tokens.Save(userID, token); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// A fresh session ID after login, so an ID planted before it is worthless
+	sessionstore.Rotate(session)
 	session.Set(constants.UserId, userID)
 	if err = session.Save(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	h.hhClient.SetAccessToken(accessToken)
-
-	c.JSON(http.StatusOK, gin.H{"message": "authorized", "user_id": userID, "access_token": accessToken})
+	c.Redirect(http.StatusFound, "/")
 }
 
 // GetUserResumes retrieves user resumes
 func (h *HHHandler) GetUserResumes(c *gin.Context) {
 	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
+	hhClient := h.hhClient.WithToken(userToken(c))
 
-	resumes, err := h.hhClient.GetResumes()
+	resumes, err := hhClient.GetResumes(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting resumes"})
+		respondError(c, err)
 		return
 	}
 
 	if len(resumes.Items) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "resumes not found"})
+		respondError(c, clients.NewError(clients.KindNotFound, "no_resumes", "user has no resumes on hh.ru"))
 		return
 	}
 
@@ -96,7 +148,7 @@ func (h *HHHandler) GetUserResumes(c *gin.Context) {
 
 	session.Set(constants.UserResume, userResumes)
 	if err = session.Save(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -105,18 +157,17 @@ func (h *HHHandler) GetUserResumes(c *gin.Context) {
 
 // GetCurrentResume retrieves the current resume from session
 func (h *HHHandler) GetCurrentResume(c *gin.Context) {
-	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
+	hhClient := h.hhClient.WithToken(userToken(c))
 
-	resumeID, ok := session.Get(constants.CurrentResumeID).(string)
-	if !ok || resumeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "resume ID not found in session"})
+	resumeID, err := currentResumeID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	resume, err := h.hhClient.GetResume(resumeID)
+	resume, err := hhClient.GetResume(c.Request.Context(), resumeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting resume"})
+		respondError(c, err)
 		return
 	}
 
@@ -127,22 +178,63 @@ func (h *HHHandler) GetCurrentResume(c *gin.Context) {
 func (h *HHHandler) GetVacancyByID(c *gin.Context) {
 	vacancyID := c.Param("vacancy_id")
 	if vacancyID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "vacancy ID is required"})
+		respondError(c, errVacancyIDRequired)
 		return
 	}
 
-	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
+	hhClient := h.hhClient.WithToken(userToken(c))
 
-	vacancy, err := h.hhClient.GetVacancyByID(vacancyID)
+	vacancy, err := hhClient.GetVacancyByID(c.Request.Context(), vacancyID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, vacancy)
 }
 
+// bindSearchParams reads vacancy filters from the query string. Cities given as
+// ?city=Москва are resolved to areas, and filter values are checked against
+// the hh.ru dictionaries.
+func (h *HHHandler) bindSearchParams(c *gin.Context) (models.VacancySearchParams, error) {
+	var params models.VacancySearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		return params, clients.NewError(clients.KindValidationFailed, "invalid_search_params", err.Error())
+	}
+	if h.dictionaries == nil {
+		return params, nil
+	}
+
+	for _, city := range c.QueryArray("city") {
+		area, err := h.dictionaries.ResolveArea(city)
+		if err != nil {
+			return params, err
+		}
+		params.Area = append(params.Area, area.ID)
+	}
+
+	return params, h.dictionaries.ValidateSearch(params)
+}
+
+// SearchVacancies searches hh.ru vacancies by the filters from the query string
+func (h *HHHandler) SearchVacancies(c *gin.Context) {
+	params, err := h.bindSearchParams(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	hhClient := h.hhClient.WithToken(userToken(c))
+
+	vacancies, err := hhClient.SearchVacancies(c.Request.Context(), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, vacancies)
+}
+
 // SetCurrnetResume select resume by title and save it in session
 func (h *HHHandler) SetCurrnetResume(c *gin.Context) {
 	type titleReq struct {
@@ -150,21 +242,16 @@ func (h *HHHandler) SetCurrnetResume(c *gin.Context) {
 	}
 	var req titleReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed parsing request"})
+		respondError(c, errBadRequestBody)
 		return
 	}
 	req.Title = strings.ToLower(req.Title)
 
 	session := sessions.Default(c)
-	resumesRaw := session.Get(constants.UserResume)
-	if resumesRaw == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
-		return
-	}
-
-	userResumes, ok := resumesRaw.([]models.SessionResume)
+	userResumes, ok := session.Get(constants.UserResume).([]models.SessionResume)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
+		respondError(c, clients.NewError(clients.KindValidationFailed, "resumes_not_loaded",
+			"load resumes with GET /api/resumes first"))
 		return
 	}
 
@@ -174,7 +261,7 @@ func (h *HHHandler) SetCurrnetResume(c *gin.Context) {
 			resumeID = resume.ID
 			session.Set(constants.CurrentResumeID, resumeID)
 			if err := session.Save(); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving session"})
+				respondError(c, err)
 				return
 			}
 			c.JSON(http.StatusOK, gin.H{
@@ -185,92 +272,72 @@ func (h *HHHandler) SetCurrnetResume(c *gin.Context) {
 			return
 		}
 	}
-	c.JSON(http.StatusNotFound, gin.H{"error": "resume not found"})
+	respondError(c, clients.NewError(clients.KindNotFound, "resume_not_found", "no resume matches the title"))
 }
 
-// // GetUserApplications получает список вакансий, на которые пользователь откликнулся
-func (h *HHHandler) GetUserApplications(c *gin.Context) {
-	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
-
-	applications, err := h.hhClient.GetUserApplications()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения откликов"})
+// GetNegotiations returns a page of the user's applications. Each item carries
+// has_updates, so clients can highlight the ones with unread changes.
+func (h *HHHandler) GetNegotiations(c *gin.Context) {
+	var params models.NegotiationsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "invalid_negotiations_params", err.Error()))
 		return
 	}
 
-	if len(applications) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Откликов не найдено"})
+	hhClient := h.hhClient.WithToken(userToken(c))
+
+	negotiations, err := hhClient.GetNegotiations(c.Request.Context(), params)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, applications)
-
-	// resp, err := h.hhService.GetClient().R().
-	// 	SetHeader("Authorization", "Bearer "+accessToken).
-	// 	Get(h.hhService.GetAPIURL() + "/negotiations")
-
-	// if err != nil || resp.StatusCode() != http.StatusOK {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения откликов"})
-	// 	return
-	// }
-
-	// var applicationsResponse models.APIApplicationsResponse
-	// if err := json.Unmarshal(resp.Body(), &applicationsResponse); err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки ответа HH API"})
-	// 	return
-	// }
-
-	// if len(applicationsResponse.Items) == 0 {
-	// 	c.JSON(http.StatusNotFound, gin.H{"error": "Откликов не найдено"})
-	// 	return
-	// }
-
-	// c.JSON(http.StatusOK, applicationsResponse)
+	c.JSON(http.StatusOK, negotiations)
 }
 
 // // GetFirstSimilarVacancy get a first similar vacancy
 func (h *HHHandler) GetFirstSimilarVacancy(c *gin.Context) {
 	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
+	hhClient := h.hhClient.WithToken(userToken(c))
 
-	resumeID, ok := session.Get(constants.CurrentResumeID).(string)
-	if !ok || resumeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "resume ID not found in session"})
+	resumeID, err := currentResumeID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	applicationsResponse, err := h.hhClient.GetSuitableVacancies(resumeID, map[string]string{"per_page": "1"})
+	vacancy, err := hhClient.GetFirstSuitableVacancy(c.Request.Context(), resumeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения откликов"})
+		respondError(c, err)
 		return
 	}
 
-	nid := applicationsResponse[0].ID
-	session.Set("nid", nid)
+	session.Set("nid", vacancy.ID)
 	session.Save()
 
-	c.JSON(http.StatusOK, applicationsResponse[0])
+	c.JSON(http.StatusOK, vacancy)
 }
 
-// GetSimilarVacancies get all similar vacancies
+// GetSimilarVacancies returns a page of vacancies similar to the current resume.
+// Accepts page, per_page and the vacancy search filters as query parameters.
 func (h *HHHandler) GetSimilarVacancies(c *gin.Context) {
-	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
-
-	resumeID, ok := session.Get(constants.CurrentResumeID).(string)
-	if !ok || resumeID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "resume ID not found in session"})
+	params, err := h.bindSearchParams(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	vacancies, err := h.hhClient.GetSuitableVacancies(resumeID, nil)
+	hhClient := h.hhClient.WithToken(userToken(c))
+
+	resumeID, err := currentResumeID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting similar vacancies"})
+		respondError(c, err)
 		return
 	}
-	if len(vacancies) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "similar vacancies not found"})
+
+	vacancies, err := hhClient.GetShortSuitableVacancies(c.Request.Context(), resumeID, params)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -278,34 +345,27 @@ func (h *HHHandler) GetSimilarVacancies(c *gin.Context) {
 }
 
 func (h *HHHandler) CreateCoverLetter(c *gin.Context) {
-	session := sessions.Default(c)
-	h.hhClient.SetAccessToken(session.Get(constants.AccessToken).(string))
+	hhClient := h.hhClient.WithToken(userToken(c))
 
-	currentResume := session.Get(constants.CurrentResumeID)
-	if currentResume == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
-		return
-	}
-
-	resumeID, ok := currentResume.(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "get user resumes error"})
+	resumeID, err := currentResumeID(c)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	firstSimilarVacancy, err := h.hhClient.GetFirstSuitableVacancy(resumeID)
+	firstSimilarVacancy, err := hhClient.GetFirstSuitableVacancy(c.Request.Context(), resumeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting similar vacancies"})
+		respondError(c, err)
 		return
 	}
 
-	vacancy, err := h.hhClient.GetVacancyByID(firstSimilarVacancy.ID)
+	vacancy, err := hhClient.GetVacancyByID(c.Request.Context(), firstSimilarVacancy.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting similar vacancies"})
+		respondError(c, err)
 		return
 	}
 
-	vacancyPromt := vacancy.ToShort()
+	vacancyPromt := vacancy.ToShort(nil)
 	_ = vacancyPromt
 
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMessages returns a page of the negotiation thread. With ?mark_read=true the
+// thread is also marked as read.
+func (h *HHHandler) GetMessages(c *gin.Context) {
+	var query struct {
+		Page     int  `form:"page"`
+		PerPage  int  `form:"per_page"`
+		MarkRead bool `form:"mark_read"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "invalid_query", err.Error()))
+		return
+	}
+
+	hhClient := h.hhClient.WithToken(userToken(c))
+	nid := c.Param("nid")
+
+	messages, err := hhClient.GetNegotiationMessages(c.Request.Context(), nid, query.Page, query.PerPage)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if query.MarkRead {
+		if err = hhClient.MarkNegotiationsRead(c.Request.Context(), nid); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// SendMessage replies to the employer in the negotiation thread
+func (h *HHHandler) SendMessage(c *gin.Context) {
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, errBadRequestBody)
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		respondError(c, clients.NewError(clients.KindValidationFailed, "text_required", "message text is required"))
+		return
+	}
+
+	hhClient := h.hhClient.WithToken(userToken(c))
+
+	if err := hhClient.SendMessage(c.Request.Context(), c.Param("nid"), req.Text); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "message sent", "negotiation_id": c.Param("nid")})
+}
+
+// MarkRead marks the negotiation thread as read
+func (h *HHHandler) MarkRead(c *gin.Context) {
+	hhClient := h.hhClient.WithToken(userToken(c))
+
+	if err := hhClient.MarkNegotiationsRead(c.Request.Context(), c.Param("nid")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+var authErrorPage = template.Must(template.New("auth-error").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+	<meta charset="utf-8">
+	<title>Ошибка авторизации</title>
+</head>
+<body>
+	<h1>Не удалось войти через hh.ru</h1>
+	<p>{{.Message}}</p>
+	<p><a href="/auth">Попробовать снова</a></p>
+</body>
+</html>
+`))
+
+var landingPage = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+	<meta charset="utf-8">
+	<title>Cover letter generator</title>
+</head>
+<body>
+	<h1>Cover letter generator</h1>
+	{{if .UserID}}
+	<p>Вы вошли через hh.ru (id {{.UserID}}).</p>
+	<p>Для доступа без браузера выпустите API-ключ: <code>POST /api/tokens</code>.</p>
+	{{else}}
+	<p><a href="/auth">Войти через hh.ru</a></p>
+	{{end}}
+</body>
+</html>
+`))
+
+// LandingHandler renders the page users land on after login
+func LandingHandler(c *gin.Context) {
+	userID, _ := sessions.Default(c).Get(constants.UserId).(string)
+	renderPage(c, http.StatusOK, landingPage, gin.H{"UserID": userID})
+}
+
+// renderAuthError renders a human-readable page for failed OAuth logins
+func renderAuthError(c *gin.Context, status int, message string) {
+	renderPage(c, status, authErrorPage, gin.H{"Message": message})
+}
+
+func renderPage(c *gin.Context, status int, page *template.Template, data gin.H) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := page.Execute(c.Writer, data); err != nil {
+		logger.Errorf("failed to render %s page: %v", page.Name(), err)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// textGenerator выбирает LLM-провайдера запроса: ?provider=, затем
+// сохраненный выбор пользователя, затем провайдер по умолчанию. Пользователю,
+// израсходовавшему месячный бюджет, провайдер не выдается.
+func (ap *ApplicationHandler) textGenerator(c *gin.Context) (string, services.LLMProvider, error) {
+	registry := ap.service.TextGenerators
+	userID, _ := userToken(c)
+	if err := ap.usage.CheckBudget(userID); err != nil {
+		return "", nil, err
+	}
+
+	name := c.Query("provider")
+	if name == "" {
+		settings, err := ap.settings.Get(userID)
+		if err != nil {
+			return "", nil, err
+		}
+		// Выбранный провайдер мог исчезнуть из конфигурации после перезапуска
+		if registry.Has(settings.LLMProvider) {
+			name = settings.LLMProvider
+		}
+	}
+	if name == "" {
+		name = registry.Default()
+	}
+
+	provider, err := registry.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, provider, nil
+}
+
+// GetLLMProviders lists the configured LLM providers and the one used for the user
+func (ap *ApplicationHandler) GetLLMProviders(c *gin.Context) {
+	userID, _ := userToken(c)
+	settings, err := ap.settings.Get(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": ap.service.TextGenerators.Names(),
+		"default":   ap.service.TextGenerators.Default(),
+		"selected":  settings.LLMProvider,
+	})
+}
+
+// GetSettings returns the settings of the current user
+func (ap *ApplicationHandler) GetSettings(c *gin.Context) {
+	userID, _ := userToken(c)
+	settings, err := ap.settings.Get(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings replaces the settings of the current user. An empty
+// llm_provider resets the choice to the default provider.
+func (ap *ApplicationHandler) UpdateSettings(c *gin.Context) {
+	var settings models.UserSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		respondError(c, errBadRequestBody)
+		return
+	}
+	if settings.LLMProvider != "" {
+		if _, err := ap.service.TextGenerators.Get(settings.LLMProvider); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	userID, _ := userToken(c)
+	if err := ap.settings.Save(userID, &settings); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/middleware"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamCoverLetter generates a letter like GenerateCoverLetter but relays it
+// over SSE while the model writes it. Errors before generation starts are
+// ordinary JSON responses; after that the stream carries the events
+//
+//	start — {"provider", "vacancy", "resume"}, the selected provider
+//	delta — {"text"}, the next piece of the letter
+//	done  — {"cover_letter", "draft_id", "provider", "vacancy", "resume", "usage"},
+//	        the provider that actually wrote the letter
+//	error — {"error": {...}} in the format of middleware.ErrorBody
+func (ap *ApplicationHandler) StreamCoverLetter(c *gin.Context) {
+	job, err := ap.prepareCoverLetter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx, gen := ap.startGeneration(c, models.GenerationCoverLetter, job.providerName)
+	send := func(event string, data any) error {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+		// Клиент ушел: прекращаем генерацию, чтобы не платить за ненужные токены
+		return ctx.Err()
+	}
+
+	// Поток длится, сколько пишет модель, и ограничен тайм-аутом провайдера.
+	// Общий HTTP_WRITE_TIMEOUT оборвал бы его молча, без события done или error.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.Warnf("%s %s: cannot lift the write deadline: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx не должен копить поток
+	_ = send("start", gin.H{"provider": job.providerName, "vacancy": job.vacancy.ID, "resume": job.resume.ID})
+
+	onDelta := func(delta string) error {
+		return send("delta", gin.H{"text": delta})
+	}
+
+	var coverLetter string
+	if streamer, ok := job.generator.(services.CoverLetterStreamer); ok {
+		coverLetter, err = streamer.StreamCoverLetter(ctx, job.resume, job.vacancy, onDelta)
+	} else {
+		coverLetter, err = job.generator.GenerateCoverLetter(ctx, job.resume, job.vacancy)
+		if err == nil {
+			err = onDelta(coverLetter)
+		}
+	}
+	usage := gen.finish(job.vacancy.ID)
+
+	if ctx.Err() != nil {
+		logger.Infof("%s %s: client disconnected during generation", c.Request.Method, c.Request.URL.Path)
+		return
+	}
+	if err == nil {
+		job.providerName = gen.providerName()
+		var draft *models.CoverLetterDraft
+		if draft, err = ap.saveCoverLetter(job, coverLetter, usage); err == nil {
+			_ = send("done", gin.H{
+				"cover_letter": coverLetter,
+				"draft_id":     draft.ID,
+				"provider":     job.providerName,
+				"vacancy":      job.vacancy.ID,
+				"resume":       job.resume.ID,
+				"usage":        usage,
+			})
+			return
+		}
+	}
+
+	status, body := middleware.ErrorResponse(err)
+	if status >= http.StatusInternalServerError {
+		logger.Errorf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	} else {
+		logger.Infof("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	_ = send("error", gin.H{"error": body})
+}
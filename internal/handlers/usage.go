@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generation отслеживает одну генерацию текста: какой провайдер ответил и
+// сколько токенов на это ушло
+type generation struct {
+	usage        *services.UsageService
+	record       models.GenerationUsage
+	usedProvider func() string
+	tokens       func() []clients.LLMUsage
+}
+
+// startGeneration возвращает контекст, с которым вызывается LLM-провайдер
+func (ap *ApplicationHandler) startGeneration(c *gin.Context, kind, providerName string) (context.Context, *generation) {
+	userID, _ := userToken(c)
+	ctx, usedProvider := services.TrackProvider(c.Request.Context())
+	ctx, tokens := clients.TrackUsage(ctx)
+	return ctx, &generation{
+		usage:        ap.usage,
+		record:       models.GenerationUsage{UserID: userID, Kind: kind, Provider: providerName},
+		usedProvider: usedProvider,
+		tokens:       tokens,
+	}
+}
+
+// providerName возвращает провайдера, написавшего текст: для цепочки
+// fallback — ответившее звено, иначе выбранный провайдер
+func (g *generation) providerName() string {
+	if name := g.usedProvider(); name != "" {
+		return name
+	}
+	return g.record.Provider
+}
+
+// finish записывает расход токенов и возвращает его, nil — если провайдер
+// его не сообщил. Вызывается и после ошибки: токены могли быть потрачены.
+func (g *generation) finish(vacancyID string) *models.TokenUsage {
+	g.record.Provider = g.providerName()
+	g.record.VacancyID = vacancyID
+	record, err := g.usage.Record(&g.record, g.tokens())
+	if err != nil {
+		logger.Errorf("failed to record LLM usage of user %s: %v", g.record.UserID, err)
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+	return &record.TokenUsage
+}
+
+// GetUsage returns the tokens and money the current user spent on generations
+// in a month: ?month=2006-01, the current one by default
+func (ap *ApplicationHandler) GetUsage(c *gin.Context) {
+	month := time.Now()
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			respondError(c, clients.NewError(clients.KindValidationFailed, "invalid_month", "month must look like 2006-01"))
+			return
+		}
+		month = parsed
+	}
+
+	userID, _ := userToken(c)
+	summary, err := ap.usage.Summary(userID, month)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -1,8 +1,75 @@
 package helpers
 
-import "github.com/rustamnr/cover-letter-generator/internal/constants"
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"strings"
 
-func GetAuthURL(clientID, redirectURI string) string {
-	return constants.HHURL + constants.Authorize + "?response_type=code&client_id=" +
-		clientID + "&redirect_uri=" + redirectURI
+	"github.com/rustamnr/cover-letter-generator/internal/constants"
+)
+
+// GetAuthURL builds the hh.ru authorization URL. redirectURI is omitted when empty,
+// in which case hh.ru uses the one registered for the application.
+func GetAuthURL(baseURL, clientID, redirectURI, state string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", clientID)
+	query.Set("state", state)
+	if redirectURI != "" {
+		query.Set("redirect_uri", redirectURI)
+	}
+	return baseURL + constants.Authorize + "?" + query.Encode()
+}
+
+// RandomToken returns a URL-safe random string built from n random bytes
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hhHosts — домены сайтов hh.ru, ссылки на вакансии которых мы принимаем
+var hhHosts = []string{"hh.ru", "hh.kz", "hh.uz", "rabota.by"}
+
+// IsVacancyID проверяет, что id похож на ID вакансии hh.ru — непустое число
+func IsVacancyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// VacancyIDFromURL извлекает ID вакансии из ссылки вида
+// https://spb.hh.ru/vacancy/123456?from=search или https://api.hh.ru/vacancies/123456
+func VacancyIDFromURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	known := false
+	for _, h := range hhHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || (parts[0] != "vacancy" && parts[0] != "vacancies") || !IsVacancyID(parts[1]) {
+		return "", false
+	}
+	return parts[1], true
 }
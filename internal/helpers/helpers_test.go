@@ -0,0 +1,45 @@
+package helpers
+
+import "testing"
+
+func TestIsVacancyID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"123456":   true,
+		"0":        true,
+		"":         false,
+		"12a":      false,
+		"-1":       false,
+		"42/../me": false,
+		"４２":       false,
+	} {
+		if got := IsVacancyID(id); got != want {
+			t.Errorf("IsVacancyID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestVacancyIDFromURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://hh.ru/vacancy/123456":               "123456",
+		"https://spb.hh.ru/vacancy/123456?from=main": "123456",
+		"http://HH.RU/vacancy/123456/":               "123456",
+		"  https://hh.kz/vacancy/7  ":                "7",
+		"https://rabota.by/vacancy/8#apply":          "8",
+		"https://api.hh.ru/vacancies/9":              "9",
+		"https://hh.ru/resume/123456":                "",
+		"https://hh.ru/vacancy/abc":                  "",
+		"https://hh.ru/vacancy/1/2":                  "",
+		"https://hh.ru/vacancy/":                     "",
+		"https://example.com/vacancy/1":              "",
+		"https://nothh.ru/vacancy/1":                 "",
+		"https://hh.ru.example.com/vacancy/1":        "",
+		"ftp://hh.ru/vacancy/1":                      "",
+		"hh.ru/vacancy/1":                            "",
+		"":                                           "",
+	} {
+		got, ok := VacancyIDFromURL(raw)
+		if got != want || ok != (want != "") {
+			t.Errorf("VacancyIDFromURL(%q) = %q, %v, want %q", raw, got, ok, want)
+		}
+	}
+}
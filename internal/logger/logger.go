@@ -5,12 +5,16 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func InitLogger() {
+func InitLogger(level string) {
 	// Set the global time field format
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
 	// Set the global log level
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil {
+		logLevel = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(logLevel)
 
 	// Output to console
 	// log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
@@ -30,6 +34,14 @@ func Infof(format string, v ...interface{}) {
 	log.Info().Msgf(format, v...)
 }
 
+func Warn(msg string) {
+	log.Warn().Msg(msg)
+}
+
+func Warnf(format string, v ...interface{}) {
+	log.Warn().Msgf(format, v...)
+}
+
 func Error(msg string) {
 	log.Error().Msg(msg)
 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest — нестандартный статус nginx для запросов, прерванных клиентом
+const statusClientClosedRequest = 499
+
+// ErrorBody — единый формат ошибок API: {"error": {...}}
+type ErrorBody struct {
+	Code    string `json:"code"`              // Машиночитаемый код, например "token_expired"
+	Kind    string `json:"kind"`              // Класс ошибки, например "unauthorized"
+	Message string `json:"message"`           // Описание для человека
+	Service string `json:"service,omitempty"` // Внешний сервис, вернувший ошибку
+}
+
+// ErrorHandler превращает ошибки, добавленные обработчиками через c.Error,
+// в ответ с HTTP-статусом по классу ошибки
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		status, body := ErrorResponse(err)
+		if status >= http.StatusInternalServerError {
+			logger.Errorf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		} else {
+			logger.Infof("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+
+		c.JSON(status, gin.H{"error": body})
+	}
+}
+
+// ErrorResponse выбирает HTTP-статус и тело ответа для ошибки. Нужен и там,
+// где ответ уже начат, например для события error в потоке SSE.
+func ErrorResponse(err error) (int, ErrorBody) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, ErrorBody{Code: "request_canceled", Kind: "canceled", Message: "request canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, ErrorBody{Code: "upstream_timeout", Kind: string(clients.KindUpstreamUnavailable), Message: "upstream service did not respond in time"}
+	}
+
+	e, ok := clients.AsError(err)
+	if !ok {
+		return http.StatusInternalServerError, ErrorBody{Code: "internal_error", Kind: "internal", Message: "internal server error"}
+	}
+
+	body := ErrorBody{Code: e.Code, Kind: string(e.Kind), Message: e.Message, Service: e.Service}
+	if body.Code == "" {
+		body.Code = string(e.Kind)
+	}
+
+	switch e.Kind {
+	case clients.KindNotFound:
+		return http.StatusNotFound, body
+	case clients.KindUnauthorized:
+		return http.StatusUnauthorized, body
+	case clients.KindForbidden:
+		return http.StatusForbidden, body
+	case clients.KindRateLimited:
+		return http.StatusTooManyRequests, body
+	case clients.KindValidationFailed:
+		return http.StatusBadRequest, body
+	default:
+		return http.StatusBadGateway, body
+	}
+}
+
+// abortWithError прерывает цепочку обработчиков, оставляя ответ ErrorHandler
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -1,49 +1,84 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
 	"github.com/rustamnr/cover-letter-generator/internal/constants"
+	"github.com/rustamnr/cover-letter-generator/internal/services"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware проверяет наличие accessToken в сессии или заголовке Authorization
-func AuthMiddleware() gin.HandlerFunc {
+// Способы аутентификации, см. constants.AuthMethod
+const (
+	AuthSession = "session"
+	AuthAPIKey  = "api_key"
+)
+
+// AuthMiddleware определяет пользователя по сессии или по API-ключу из заголовка
+// Authorization и подставляет в контекст его токен hh.ru
+func AuthMiddleware(tokens storage.TokenStore, apiKeys *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
 
-		// Попробуем получить токен из сессии
-		accessToken, ok := session.Get(constants.AccessToken).(string)
-		if !ok || accessToken == "" {
-			// Если токена нет в сессии, попробуем получить его из заголовка Authorization
+		// Попробуем получить пользователя из сессии
+		userID, _ := session.Get(constants.UserId).(string)
+		method := AuthSession
+		if userID == "" {
+			// Если пользователя нет в сессии, попробуем найти его по API-ключу
 			authHeader := c.GetHeader("Authorization")
 			const bearerPrefix = "Bearer "
 
 			if strings.HasPrefix(authHeader, bearerPrefix) {
-				accessToken = strings.TrimPrefix(authHeader, bearerPrefix)
-
-				// Сохраняем токен в сессии
-				session.Set(constants.AccessToken, accessToken)
-				if err := session.Save(); err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
-					c.Abort()
+				key, err := apiKeys.Authenticate(strings.TrimPrefix(authHeader, bearerPrefix))
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					abortWithError(c, clients.NewError(clients.KindUnauthorized, "invalid_api_key", "API key is invalid or revoked"))
+					return
+				}
+				if err != nil {
+					abortWithError(c, err)
 					return
 				}
+				userID = key.UserID
+				method = AuthAPIKey
 			}
 		}
 
-		// Если токен отсутствует, возвращаем ошибку
-		if accessToken == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token is missing"})
-			c.Abort()
+		// Если пользователь не определен, возвращаем ошибку
+		if userID == "" {
+			abortWithError(c, clients.NewError(clients.KindUnauthorized, "authorization_missing", "Authorization is missing"))
+			return
+		}
+
+		token, err := tokens.Get(userID)
+		if err != nil {
+			abortWithError(c, clients.NewError(clients.KindUnauthorized, "hh_authorization_missing",
+				"hh.ru authorization is missing, authorize again"))
 			return
 		}
 
-		// Сохраняем токен в контексте Gin
-		c.Set(constants.AccessToken, accessToken)
+		// Сохраняем пользователя и токен в контексте Gin
+		c.Set(constants.UserId, userID)
+		c.Set(constants.HHToken, token)
+		c.Set(constants.AuthMethod, method)
+		c.Next()
+	}
+}
+
+// SessionOnly пускает только пользователей, вошедших через браузер. Нужен там,
+// где API-ключ не должен давать больше, чем он сам: например при выпуске новых
+// ключей, иначе утекший ключ пережил бы свой отзыв.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(constants.AuthMethod) != AuthSession {
+			abortWithError(c, clients.NewError(clients.KindForbidden, "session_required",
+				"this action requires signing in through the browser, not an API key"))
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// APIKey — выданный нами ключ доступа к API для клиентов без браузера.
+// Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	UserID     string     `json:"-"`
+	Hash       string     `json:"-"`
+	Prefix     string     `json:"prefix"` // Начало ключа, чтобы пользователь мог его узнать
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Справочники hh.ru, по которым проверяются фильтры и переводятся ID
+const (
+	DictExperience       = "experience"
+	DictEmployment       = "employment"
+	DictSchedule         = "schedule"
+	DictCurrency         = "currency"
+	DictWorkFormat       = "work_format"
+	DictSearchOrder      = "vacancy_search_order"
+	DictArea             = "areas"
+	DictProfessionalRole = "professional_roles"
+)
+
+// DictionaryItem — элемент справочника. У валют hh.ru вместо id отдает code.
+type DictionaryItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AreaTree — регион из /areas с вложенными регионами
+type AreaTree struct {
+	ID       string     `json:"id"`
+	ParentID *string    `json:"parent_id"`
+	Name     string     `json:"name"`
+	Areas    []AreaTree `json:"areas"`
+}
+
+// ProfessionalRoleCategory — категория из /professional_roles
+type ProfessionalRoleCategory struct {
+	ID    string             `json:"id"`
+	Name  string             `json:"name"`
+	Roles []ProfessionalRole `json:"roles"`
+}
+
+// ProfessionalRolesResponse — ответ /professional_roles
+type ProfessionalRolesResponse struct {
+	Categories []ProfessionalRoleCategory `json:"categories"`
+}
+
+// Dictionaries — снимок справочников hh.ru
+type Dictionaries struct {
+	FetchedAt         time.Time                   `json:"fetched_at"`
+	Dictionaries      map[string][]DictionaryItem `json:"dictionaries"` // /dictionaries по ключам вроде DictExperience
+	Areas             []AreaTree                  `json:"areas"`
+	ProfessionalRoles []ProfessionalRoleCategory  `json:"professional_roles"`
+}
+
+// NameResolver переводит ID из справочника в название
+type NameResolver interface {
+	Name(dictionary, id string) (string, bool)
+}
+
+// resolveName подставляет в пустое name название id из справочника.
+// names может быть nil.
+func resolveName(names NameResolver, dictionary, id string, name *string) {
+	if *name != "" || id == "" || names == nil {
+		return
+	}
+	if resolved, ok := names.Name(dictionary, id); ok {
+		*name = resolved
+	}
+}
+
+// nameOrID возвращает name, а если оно пустое — id
+func nameOrID(id, name string) string {
+	if name == "" {
+		return id
+	}
+	return name
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CoverLetterDraft — сгенерированное письмо, которое пользователь может
+// просмотреть и отредактировать перед откликом
+type CoverLetterDraft struct {
+	ID        string      `json:"id"`
+	UserID    string      `json:"-"`
+	VacancyID string      `json:"vacancy_id,omitempty"` // Пусто для письма к вакансии не с hh.ru
+	ResumeID  string      `json:"resume_id,omitempty"`
+	Text      string      `json:"text"`
+	Provider  string      `json:"provider,omitempty"` // LLM-провайдер, написавший письмо
+	Usage     *TokenUsage `json:"usage,omitempty"`    // Расход на генерацию, если провайдер его сообщил
+	Edited    bool        `json:"edited"`             // Пользователь менял текст после генерации
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
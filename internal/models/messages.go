@@ -0,0 +1,64 @@
+package models
+
+import "strings"
+
+// Участники переписки по отклику
+const (
+	ParticipantApplicant = "applicant"
+	ParticipantEmployer  = "employer"
+)
+
+// NegotiationMessage — сообщение в переписке по отклику
+type NegotiationMessage struct {
+	ID               string        `json:"id"`
+	Text             string        `json:"text"`
+	CreatedAt        string        `json:"created_at"`
+	Author           MessageAuthor `json:"author"`
+	State            *State        `json:"state,omitempty"`           // Изменение состояния отклика, если сообщение его вызвало
+	ViewedByMe       bool          `json:"viewed_by_me"`              // Прочитано пользователем
+	ViewedByOpponent bool          `json:"viewed_by_opponent"`        // Прочитано работодателем
+	EditAllowed      bool          `json:"editing_allowed,omitempty"` // Можно ли отредактировать
+	Address          *Address      `json:"address,omitempty"`         // Адрес собеседования в приглашении
+}
+
+// MessageAuthor — автор сообщения
+type MessageAuthor struct {
+	ParticipantType string `json:"participant_type"` // ParticipantApplicant или ParticipantEmployer
+}
+
+// MessagesResponse — страница сообщений GET /negotiations/{nid}/messages
+type MessagesResponse struct {
+	Found   int                  `json:"found"`
+	Items   []NegotiationMessage `json:"items"`
+	Page    int                  `json:"page"`
+	Pages   int                  `json:"pages"`
+	PerPage int                  `json:"per_page"`
+}
+
+// Negotiation — отклик GET /negotiations/{nid} вместе с резюме, которым откликнулись
+type Negotiation struct {
+	ApplicationItem
+	Resume *NegotiationResume `json:"resume,omitempty"`
+}
+
+// NegotiationResume — резюме, с которым пользователь откликнулся
+type NegotiationResume struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ThreadToString записывает переписку для промпта: автор, дата и текст каждого сообщения
+func ThreadToString(messages []NegotiationMessage) string {
+	var builder strings.Builder
+
+	for _, message := range messages {
+		author := "Кандидат"
+		if message.Author.ParticipantType == ParticipantEmployer {
+			author = "Работодатель"
+		}
+		builder.WriteString(author + " (" + message.CreatedAt + "):\n")
+		builder.WriteString(strings.TrimSpace(message.Text) + "\n\n")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// hhTimeLayout — формат дат в ответах hh.ru, например 2024-05-21T12:00:00+0300
+const hhTimeLayout = "2006-01-02T15:04:05-0700"
+
+// NegotiationsParams — фильтры списка откликов GET /negotiations.
+// State, UpdatedFrom и UpdatedTo hh.ru не поддерживает: с ними отклики
+// перебираются по всем страницам hh.ru и постранично делятся уже здесь.
+type NegotiationsParams struct {
+	Status      string    `form:"status"`                                // Статус на стороне hh.ru: active, archived, ...
+	State       []string  `form:"state"`                                 // Состояние отклика: response, invitation, discard, ...
+	VacancyID   string    `form:"vacancy_id"`                            // Отклики на одну вакансию
+	HasUpdates  *bool     `form:"has_updates"`                           // Только отклики с непрочитанными изменениями
+	UpdatedFrom time.Time `form:"updated_from" time_format:"2006-01-02"` // Обновлены не раньше этой даты
+	UpdatedTo   time.Time `form:"updated_to" time_format:"2006-01-02"`   // Обновлены не позже этой даты включительно
+	OrderBy     string    `form:"order_by"`                              // created_at или updated_at
+	Order       string    `form:"order"`                                 // asc или desc
+	Page        int       `form:"page"`                                  // Номер страницы с нуля
+	PerPage     int       `form:"per_page"`                              // Размер страницы, до 100
+}
+
+// Validate проверяет значения, которые hh.ru отклонил бы с ошибкой 400
+func (p NegotiationsParams) Validate() error {
+	var errs []error
+
+	if p.OrderBy != "" && p.OrderBy != "created_at" && p.OrderBy != "updated_at" {
+		errs = append(errs, errors.New("order_by must be created_at or updated_at"))
+	}
+	if p.Order != "" && p.Order != "asc" && p.Order != "desc" {
+		errs = append(errs, errors.New("order must be asc or desc"))
+	}
+	if p.Page < 0 {
+		errs = append(errs, errors.New("page must not be negative"))
+	}
+	if p.PerPage < 0 || p.PerPage > 100 {
+		errs = append(errs, errors.New("per_page must be between 0 and 100"))
+	}
+	if !p.UpdatedFrom.IsZero() && !p.UpdatedTo.IsZero() && p.UpdatedTo.Before(p.UpdatedFrom) {
+		errs = append(errs, errors.New("updated_to must not be before updated_from"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Query возвращает параметры, которые понимает hh.ru, пропуская пустые
+func (p NegotiationsParams) Query() url.Values {
+	q := url.Values{}
+
+	if p.Status != "" {
+		q.Set("status", p.Status)
+	}
+	if p.VacancyID != "" {
+		q.Set("vacancy_id", p.VacancyID)
+	}
+	if p.HasUpdates != nil {
+		q.Set("has_updates", strconv.FormatBool(*p.HasUpdates))
+	}
+	if p.OrderBy != "" {
+		q.Set("order_by", p.OrderBy)
+	}
+	if p.Order != "" {
+		q.Set("order", p.Order)
+	}
+	if p.Page > 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(p.PerPage))
+	}
+
+	return q
+}
+
+// FiltersLocally сообщает, заданы ли фильтры, которых нет у hh.ru
+func (p NegotiationsParams) FiltersLocally() bool {
+	return len(p.State) > 0 || !p.UpdatedFrom.IsZero() || !p.UpdatedTo.IsZero()
+}
+
+// Matches применяет фильтры, которых нет у hh.ru
+func (p NegotiationsParams) Matches(item ApplicationItem) bool {
+	if len(p.State) > 0 && (item.State == nil || item.State.ID == nil || !slices.Contains(p.State, *item.State.ID)) {
+		return false
+	}
+
+	if p.UpdatedFrom.IsZero() && p.UpdatedTo.IsZero() {
+		return true
+	}
+
+	updated := item.CreatedAt
+	if item.UpdatedAt != nil && *item.UpdatedAt != "" {
+		updated = *item.UpdatedAt
+	}
+	updatedAt, err := time.Parse(hhTimeLayout, updated)
+	if err != nil {
+		return false
+	}
+
+	if !p.UpdatedFrom.IsZero() && updatedAt.Before(p.UpdatedFrom) {
+		return false
+	}
+	// UpdatedTo — дата без времени, включаем весь день
+	if !p.UpdatedTo.IsZero() && !updatedAt.Before(p.UpdatedTo.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Типы вопросов анкеты работодателя
+const (
+	QuestionText     = "text"     // Свободный ответ
+	QuestionSingle   = "single"   // Один вариант из списка
+	QuestionMultiple = "multiple" // Несколько вариантов из списка
+)
+
+// Test — анкета (тест) работодателя, которую нужно заполнить при отклике
+type Test struct {
+	ID          string         `json:"id,omitempty"`
+	Required    bool           `json:"required"`
+	Description string         `json:"description,omitempty"`
+	Questions   []TestQuestion `json:"questions,omitempty"`
+}
+
+// TestQuestion — вопрос анкеты
+type TestQuestion struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // QuestionText, QuestionSingle или QuestionMultiple
+	Text     string       `json:"text"`
+	Required bool         `json:"required"`
+	Options  []TestOption `json:"options,omitempty"`
+}
+
+// TestOption — вариант ответа на вопрос с выбором
+type TestOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// TestAnswer — ответ на вопрос анкеты: текст или выбранные варианты
+type TestAnswer struct {
+	QuestionID string   `json:"question_id"`
+	Text       string   `json:"text,omitempty"`
+	OptionIDs  []string `json:"option_ids,omitempty"`
+}
+
+// ToString записывает анкету для промпта: ID, тип, текст и варианты вопросов
+func (t *Test) ToString() string {
+	var builder strings.Builder
+
+	builder.WriteString("Анкета работодателя:\n")
+	if t.Description != "" {
+		builder.WriteString(t.Description + "\n")
+	}
+	for _, question := range t.Questions {
+		builder.WriteString(fmt.Sprintf("\nВопрос %s (%s", question.ID, question.Type))
+		if question.Required {
+			builder.WriteString(", обязательный")
+		}
+		builder.WriteString("): " + question.Text + "\n")
+		for _, option := range question.Options {
+			builder.WriteString(fmt.Sprintf("- вариант %s: %s\n", option.ID, option.Text))
+		}
+	}
+
+	return builder.String()
+}
@@ -287,7 +287,9 @@ func (r *Resume) getBasicInfo() string {
 	}
 	sb.WriteString(fmt.Sprintf("Дата создания: %s\n", r.CreatedAt))
 	sb.WriteString(fmt.Sprintf("Дата обновления: %s\n", r.UpdatedAt))
-	sb.WriteString(fmt.Sprintf("Статус: %s\n", r.Status.Name))
+	if r.Status.Name != nil {
+		sb.WriteString(fmt.Sprintf("Статус: %s\n", *r.Status.Name))
+	}
 	return sb.String()
 }
 
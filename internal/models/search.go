@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// MaxSearchDepth — hh.ru отдает не больше 2000 вакансий на один поисковый запрос
+const MaxSearchDepth = 2000
+
+// DefaultPerPage — размер страницы hh.ru, если per_page не задан
+const DefaultPerPage = 20
+
+// VacancySearchParams — фильтры поиска вакансий GET /vacancies.
+// Поля-списки можно передавать несколько раз: ?area=1&area=2.
+type VacancySearchParams struct {
+	Text             string   `form:"text" json:"text,omitempty"`                           // Поисковый запрос
+	Area             []string `form:"area" json:"area,omitempty"`                           // Регион, справочник /areas
+	Salary           int      `form:"salary" json:"salary,omitempty"`                       // Желаемая зарплата
+	Currency         string   `form:"currency" json:"currency,omitempty"`                   // Валюта зарплаты, например RUR
+	OnlyWithSalary   bool     `form:"only_with_salary" json:"only_with_salary,omitempty"`   // Только вакансии с указанной зарплатой
+	Experience       []string `form:"experience" json:"experience,omitempty"`               // Опыт работы, справочник experience
+	Employment       []string `form:"employment" json:"employment,omitempty"`               // Тип занятости, справочник employment
+	Schedule         []string `form:"schedule" json:"schedule,omitempty"`                   // График работы, справочник schedule
+	ProfessionalRole []string `form:"professional_role" json:"professional_role,omitempty"` // Профессиональная роль
+	WorkFormat       []string `form:"work_format" json:"work_format,omitempty"`             // Формат работы, справочник work_format
+	Period           int      `form:"period" json:"period,omitempty"`                       // За сколько дней искать, до 30
+	OrderBy          string   `form:"order_by" json:"order_by,omitempty"`                   // Сортировка, справочник vacancy_search_order
+	Page             int      `form:"page" json:"page,omitempty"`                           // Номер страницы с нуля
+	PerPage          int      `form:"per_page" json:"per_page,omitempty"`                   // Размер страницы, до 100
+}
+
+// Validate проверяет диапазоны, которые hh.ru отклонил бы с ошибкой 400
+func (p VacancySearchParams) Validate() error {
+	var errs []error
+
+	if p.Salary < 0 {
+		errs = append(errs, errors.New("salary must not be negative"))
+	}
+	if p.Period < 0 || p.Period > 30 {
+		errs = append(errs, errors.New("period must be between 0 and 30 days"))
+	}
+	if p.Page < 0 {
+		errs = append(errs, errors.New("page must not be negative"))
+	}
+	if p.PerPage < 0 || p.PerPage > 100 {
+		errs = append(errs, errors.New("per_page must be between 0 and 100"))
+	}
+	perPage := p.PerPage
+	if perPage == 0 {
+		perPage = DefaultPerPage
+	}
+	if (p.Page+1)*perPage > MaxSearchDepth {
+		errs = append(errs, errors.New("(page + 1) * per_page must not exceed 2000 results"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Query возвращает параметры в формате запроса hh.ru, пропуская пустые
+func (p VacancySearchParams) Query() url.Values {
+	q := url.Values{}
+
+	setString := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	setInt := func(key string, value int) {
+		if value > 0 {
+			q.Set(key, strconv.Itoa(value))
+		}
+	}
+	addAll := func(key string, values []string) {
+		for _, v := range values {
+			if v != "" {
+				q.Add(key, v)
+			}
+		}
+	}
+
+	setString("text", p.Text)
+	addAll("area", p.Area)
+	setInt("salary", p.Salary)
+	setString("currency", p.Currency)
+	if p.OnlyWithSalary {
+		q.Set("only_with_salary", "true")
+	}
+	addAll("experience", p.Experience)
+	addAll("employment", p.Employment)
+	addAll("schedule", p.Schedule)
+	addAll("professional_role", p.ProfessionalRole)
+	addAll("work_format", p.WorkFormat)
+	setInt("period", p.Period)
+	setString("order_by", p.OrderBy)
+	setInt("page", p.Page)
+	setInt("per_page", p.PerPage)
+
+	return q
+}
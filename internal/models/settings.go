@@ -0,0 +1,6 @@
+package models
+
+// UserSettings — настройки пользователя, которые он меняет через /api/settings
+type UserSettings struct {
+	LLMProvider string `json:"llm_provider"` // Пусто — провайдер по умолчанию
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	gob.Register(HHToken{})
+}
+
+// tokenExpiryLeeway — запас времени, за который токен считается истекшим,
+// чтобы не отправлять запрос с токеном, который умрёт по дороге
+const tokenExpiryLeeway = time.Minute
+
+// HHToken хранит OAuth-токены пользователя hh.ru
+type HHToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"` // Нулевое значение — срок жизни неизвестен
+}
+
+// Expired сообщает, что access token истек или вот-вот истечет
+func (t *HHToken) Expired() bool {
+	if t.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Now().Add(tokenExpiryLeeway).After(t.ExpiresAt)
+}
+
+// CanRefresh сообщает, можно ли обновить токен через grant_type=refresh_token
+func (t *HHToken) CanRefresh() bool {
+	return t.RefreshToken != ""
+}
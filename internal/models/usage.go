@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Виды генераций в учете расхода
+const (
+	GenerationCoverLetter = "cover_letter"
+	GenerationReplyDraft  = "reply_draft"
+	GenerationTestAnswers = "test_answers"
+)
+
+// TokenUsage — расход токенов и его стоимость в долларах
+type TokenUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"` // Включая cache_hit_tokens
+	CompletionTokens int     `json:"completion_tokens"`
+	CacheHitTokens   int     `json:"cache_hit_tokens"` // Токены промпта из кэша провайдера
+	Cost             float64 `json:"cost"`
+}
+
+// Add прибавляет расход other
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CacheHitTokens += other.CacheHitTokens
+	u.Cost += other.Cost
+}
+
+// GenerationUsage — расход одной генерации. Если провайдеров пробовали по
+// очереди, учитываются все их ответы.
+type GenerationUsage struct {
+	ID        string `json:"id"`
+	UserID    string `json:"-"`
+	Kind      string `json:"kind"`                 // GenerationCoverLetter, GenerationReplyDraft или GenerationTestAnswers
+	Provider  string `json:"provider"`             // Провайдер, написавший текст
+	Model     string `json:"model,omitempty"`      // Модель последнего ответа
+	VacancyID string `json:"vacancy_id,omitempty"` // Пусто для вакансии не с hh.ru
+	TokenUsage
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UsageSummary — расход пользователя за календарный месяц
+type UsageSummary struct {
+	Month       string            `json:"month"` // 2006-01, UTC
+	Total       TokenUsage        `json:"total"`
+	Budget      float64           `json:"budget,omitempty"` // Пусто — без ограничения
+	Exceeded    bool              `json:"budget_exceeded"`
+	Generations []GenerationUsage `json:"generations"`
+}
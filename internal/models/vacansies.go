@@ -73,7 +73,9 @@ type VacancyShort struct {
 	Test                   *Test             `json:"test,omitempty"`
 }
 
-func (v *Vacancy) ToShort() *VacancyShort {
+// ToShort сокращает вакансию. По справочникам names подставляются названия,
+// которых нет в ответе hh.ru; names может быть nil.
+func (v *Vacancy) ToShort(names NameResolver) *VacancyShort {
 	// Преобразуем ключевые навыки
 	var keySkills []KeySkill
 	for _, skill := range v.KeySkills {
@@ -86,18 +88,34 @@ func (v *Vacancy) ToShort() *VacancyShort {
 		contacts = *v.Contacts
 	}
 
-	return &VacancyShort{
+	location := v.Area.Name
+	resolveName(names, DictArea, v.Area.ID, &location)
+
+	short := &VacancyShort{
 		ID:          v.ID,
 		Name:        v.Name,
 		Description: v.Description, // Если нужно, можно добавить очистку HTML-тегов
 		Contacts:    contacts,
-		Location:    v.Area.Name,
+		Location:    nameOrID(v.Area.ID, location),
 		Employment:  v.Employment,
 		Experience:  v.VacancyExperience,
 		Schedule:    v.Schedule,
 		KeySkills:   keySkills,
 		CompanyName: v.Employer.Name,
+
+		ResponseLetterRequired: v.ResponseLetterRequired,
+		Test:                   v.Test,
 	}
+	short.ResolveNames(names)
+	return short
+}
+
+// ResolveNames подставляет названия типа занятости, опыта и графика, если
+// hh.ru прислал только их ID. names может быть nil.
+func (v *VacancyShort) ResolveNames(names NameResolver) {
+	resolveName(names, DictEmployment, v.Employment.ID, &v.Employment.Name)
+	resolveName(names, DictExperience, v.Experience.ID, &v.Experience.Name)
+	resolveName(names, DictSchedule, v.Schedule.ID, &v.Schedule.Name)
 }
 
 func (v VacancyShort) ToString() string {
@@ -108,9 +126,9 @@ func (v VacancyShort) ToString() string {
 	builder.WriteString("Название: " + v.Name + "\n")
 	builder.WriteString("Компания: " + v.CompanyName + "\n")
 	builder.WriteString("Локация: " + v.Location + "\n")
-	// builder.WriteString("Тип занятости: " + v.Employment + "\n")
-	builder.WriteString("Опыт работы: " + v.Experience.Name + "\n")
-	builder.WriteString("График работы: " + v.Schedule.Name + "\n")
+	builder.WriteString("Тип занятости: " + nameOrID(v.Employment.ID, v.Employment.Name) + "\n")
+	builder.WriteString("Опыт работы: " + nameOrID(v.Experience.ID, v.Experience.Name) + "\n")
+	builder.WriteString("График работы: " + nameOrID(v.Schedule.ID, v.Schedule.Name) + "\n")
 
 	// Добавляем описание вакансии
 	builder.WriteString("\nОписание:\n")
@@ -145,7 +163,8 @@ func (v VacancyShort) ToString() string {
 	return builder.String()
 }
 
-// VacanciesResponse представляет ответ от API /resumes/{resume_id}/similar_vacancies
+// VacanciesResponse представляет постраничный ответ hh.ru: /vacancies и
+// /resumes/{resume_id}/similar_vacancies
 type VacanciesResponse[T any] struct {
 	Found   int `json:"found"`    // Количество найденных вакансий
 	Items   []T `json:"items"`    // Список вакансий
@@ -252,10 +271,6 @@ type CoverPicture struct {
 	ResizedHeight int    `json:"resized_height"`
 }
 
-type Test struct {
-	Required bool `json:"required"`
-}
-
 type Type struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -1,62 +1,239 @@
 package server
 
 import (
-	"net/http"
+	"context"
+	"fmt"
 
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
 	"github.com/rustamnr/cover-letter-generator/internal/handlers"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
 	"github.com/rustamnr/cover-letter-generator/internal/middleware"
 	"github.com/rustamnr/cover-letter-generator/internal/services"
-
-	"github.com/gin-gonic/gin"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
 )
 
-// registerRoutes настраивает маршруты API
-func registerRoutes(router *gin.Engine) {
+// registerRoutes настраивает маршруты API. Фоновые задачи регистрируют
+// свою остановку через s.OnShutdown.
+func registerRoutes(s *Server, cfg *config.Config) error {
+	router := s.Router
+
+	// Хранилища OAuth-токенов hh.ru, выданных API-ключей, черновиков писем,
+	// настроек и расхода токенов LLM
+	tokens, err := newTokenStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	apiKeyStore, err := newAPIKeyStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	apiKeys := services.NewAPIKeyService(apiKeyStore)
+	drafts := storage.NewMemoryDraftStore()
+	settings := storage.NewMemorySettingsStore()
+	usageStore, err := newUsageStore(cfg.LLM.Usage)
+	if err != nil {
+		return err
+	}
+	usage, err := services.NewUsageService(usageStore, cfg.LLM.Usage)
+	if err != nil {
+		return err
+	}
+
 	// Инициализация клиентов
-	hhClient := clients.NewHHClient()
-	deepSeekClient := clients.NewDeepSeekClient()
+	hhClient := clients.NewHHClient(cfg.HH, tokens)
+	dictionaries := startDictionaries(s, hhClient, cfg.HH)
+	// Вакансии получают названия из справочников, если hh.ru прислал только ID
+	hhClient = hhClient.WithNames(dictionaries)
+	textGenerators, err := newLLMRegistry(cfg.LLM)
+	if err != nil {
+		return err
+	}
 
-	vacancyProvider := services.NewHHProvider(hhClient)
-	textGenerator := services.NewDeepSeekService(deepSeekClient)
 	// Инициализация сервисов
-	applicationService := services.NewApplicationService(vacancyProvider, textGenerator)
+	vacancyProvider := services.NewHHProvider(hhClient)
+	applicationService := services.NewApplicationService(vacancyProvider, textGenerators)
 
 	// Инициализация хендлеров
-	hhHandler := handlers.NewHHHandler(hhClient)
-	applicationHandler := handlers.NewApplicationHandler(applicationService)
+	hhHandler := handlers.NewHHHandler(hhClient, tokens, dictionaries)
+	applicationHandler := handlers.NewApplicationHandler(applicationService, drafts, settings, usage)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeys)
 
 	// Настройка сессий
-	store := cookie.NewStore([]byte("secret"))
-	store.Options(sessions.Options{
-		Path:     "/",       // Доступность для всех путей
-		Domain:   "",        // Пусто = текущий домен
-		MaxAge:   86400 * 7, // Время жизни в секундах (7 дней)
-		Secure:   false,     // true для HTTPS только
-		HttpOnly: true,      // Запрет доступа из JavaScript
-		SameSite: http.SameSiteLaxMode,
-	})
+	store, err := newSessionStore(cfg.Session)
+	if err != nil {
+		return fmt.Errorf("failed to create session store: %w", err)
+	}
+	// Ошибки обработчиков превращаются в JSON-ответы единого формата
+	router.Use(middleware.ErrorHandler())
 	router.Use(sessions.Sessions("session", store))
 
+	router.GET("/", handlers.LandingHandler)
+
 	// HH.ru API
 	router.GET("/auth", hhHandler.AuthHandler)
 	router.GET("/auth/callback", hhHandler.CallbackHandler)
 
 	// API группы
 	api := router.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(tokens, apiKeys))
 	{
 		api.GET("/resumes", hhHandler.GetUserResumes)
 		api.POST("/resumes/current", hhHandler.SetCurrnetResume)
 		api.GET("/resumes/current", hhHandler.GetCurrentResume)
 
+		api.GET("/vacancies/search", hhHandler.SearchVacancies)
 		api.GET("/vacancies/similar", hhHandler.GetSimilarVacancies)
 		api.GET("/vacancies/similar/first", hhHandler.GetFirstSimilarVacancy)
 		api.GET("/vacancies/:vacancy_id", hhHandler.GetVacancyByID)
+		api.GET("/vacancies/:vacancy_id/test", applicationHandler.GetVacancyTest)
+		api.POST("/vacancies/:vacancy_id/test/answers", applicationHandler.ProposeTestAnswers)
 		api.POST("/vacancies/apply/:vacancy_id", applicationHandler.ApplyToVacancy)
 
+		api.GET("/negotiations", hhHandler.GetNegotiations)
+		api.GET("/negotiations/:nid/messages", hhHandler.GetMessages)
+		api.POST("/negotiations/:nid/messages", hhHandler.SendMessage)
+		api.POST("/negotiations/:nid/read", hhHandler.MarkRead)
+		api.POST("/negotiations/:nid/reply-draft", applicationHandler.DraftReply)
+
 		api.POST("/cover-letter", applicationHandler.GenerateCoverLetter)
+		api.POST("/cover-letter/stream", applicationHandler.StreamCoverLetter)
+		api.GET("/drafts/:id", applicationHandler.GetDraft)
+		api.PUT("/drafts/:id", applicationHandler.UpdateDraft)
+		api.DELETE("/drafts/:id", applicationHandler.DeleteDraft)
+
+		api.GET("/llm/providers", applicationHandler.GetLLMProviders)
+		api.GET("/settings", applicationHandler.GetSettings)
+		api.PUT("/settings", applicationHandler.UpdateSettings)
+		api.GET("/usage", applicationHandler.GetUsage)
+
+		api.GET("/dictionaries", hhHandler.GetDictionaries)
+		api.GET("/areas/resolve", hhHandler.ResolveArea)
+
+		api.GET("/tokens", apiKeyHandler.ListKeys)
+		api.POST("/tokens", middleware.SessionOnly(), apiKeyHandler.CreateKey)
+		api.DELETE("/tokens/:id", apiKeyHandler.RevokeKey)
+	}
+
+	return nil
+}
+
+// newLLMRegistry создает клиентов всех настроенных LLM-провайдеров
+func newLLMRegistry(cfg config.LLMConfig) (*services.LLMRegistry, error) {
+	registry := services.NewLLMRegistry()
+
+	for _, p := range cfg.Providers() {
+		var client clients.LLMClient
+		switch p.Name {
+		case clients.ServiceDeepSeek:
+			client = clients.NewDeepSeekClient(p.Config)
+		case clients.ServiceOpenAI:
+			client = clients.NewOpenAIClient(p.Config)
+		case clients.ServiceAnthropic:
+			client = clients.NewAnthropicClient(p.Config)
+		case clients.ServiceLocal:
+			client = clients.NewLocalLLMClient(cfg.Local)
+		default:
+			return nil, fmt.Errorf("unsupported LLM provider %q", p.Name)
+		}
+		registry.Register(p.Name, services.NewLLMService(client, cfg.MaxTokens))
+	}
+
+	// Цепочка становится провайдером по умолчанию, если он не задан явно
+	if len(cfg.Fallback.Chain) > 0 {
+		fallback, err := newFallbackProvider(registry, cfg.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(config.LLMFallbackName, fallback)
+		if cfg.Provider == "" {
+			cfg.Provider = config.LLMFallbackName
+		}
+	}
+
+	if cfg.Provider != "" {
+		if err := registry.SetDefault(cfg.Provider); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// newFallbackProvider собирает цепочку из уже созданных провайдеров
+func newFallbackProvider(registry *services.LLMRegistry, cfg config.LLMFallbackConfig) (*services.FallbackProvider, error) {
+	steps, err := cfg.Steps()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]services.FallbackStep, 0, len(steps))
+	for _, step := range steps {
+		provider, err := registry.Get(step.Name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, services.FallbackStep{Name: step.Name, Provider: provider, Timeout: step.Timeout})
+	}
+	return services.NewFallbackProvider(chain, cfg.Failures, cfg.Cooldown), nil
+}
+
+// newUsageStore открывает хранилище расхода токенов LLM
+func newUsageStore(cfg config.LLMUsageConfig) (storage.UsageStore, error) {
+	if cfg.Backend == "memory" {
+		logger.Warn("LLM_USAGE_BACKEND=memory: usage history and spent budgets are reset on every restart")
+		return storage.NewMemoryUsageStore(), nil
+	}
+	store, err := storage.NewFileUsageStore(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LLM usage file: %w", err)
+	}
+	return store, nil
+}
+
+// newTokenStore создает хранилище OAuth-токенов hh.ru по STORAGE_BACKEND
+func newTokenStore(cfg config.StorageConfig) (storage.TokenStore, error) {
+	if cfg.Backend == "memory" {
+		logger.Warn("STORAGE_BACKEND=memory: hh.ru tokens are lost on restart, every user has to sign in again")
+		return storage.NewMemoryTokenStore(), nil
 	}
+	store, err := storage.NewFileTokenStore(cfg.TokensFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hh.ru tokens file: %w", err)
+	}
+	return store, nil
+}
+
+// newAPIKeyStore создает хранилище API-ключей по STORAGE_BACKEND
+func newAPIKeyStore(cfg config.StorageConfig) (storage.APIKeyStore, error) {
+	if cfg.Backend == "memory" {
+		logger.Warn("STORAGE_BACKEND=memory: API keys stop working on restart")
+		return storage.NewMemoryAPIKeyStore(), nil
+	}
+	store, err := storage.NewFileAPIKeyStore(cfg.APIKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %w", err)
+	}
+	return store, nil
+}
+
+// startDictionaries загружает справочники hh.ru и запускает их обновление до
+// остановки сервера. Без справочников сервер работает, но не проверяет фильтры.
+func startDictionaries(s *Server, hhClient *clients.HHClient, cfg config.HHConfig) *clients.Dictionaries {
+	dictionaries := clients.NewDictionaries(hhClient, cfg.DictionariesCache, cfg.DictionariesTTL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	if err := dictionaries.Load(ctx); err != nil {
+		logger.Errorf("hh.ru dictionaries are unavailable, filters will not be validated: %v", err)
+	}
+	cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	go dictionaries.Run(ctx)
+	s.OnShutdown(func(context.Context) error {
+		stop()
+		return nil
+	})
+
+	return dictionaries
 }
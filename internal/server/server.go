@@ -1,30 +1,102 @@
 package server
 
 import (
-	"log"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
 )
 
+// ShutdownHook вызывается при остановке сервера после завершения HTTP-запросов
+type ShutdownHook func(ctx context.Context) error
+
 // Server структура для хранения объекта сервера
 type Server struct {
 	Router *gin.Engine
+
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+
+	mu    sync.Mutex
+	hooks []ShutdownHook
 }
 
 // NewServer создает новый сервер и настраивает маршруты
-func NewServer() *Server {
+func NewServer(cfg *config.Config) (*Server, error) {
 	router := gin.Default()
 
+	s := &Server{
+		Router: router,
+		httpServer: &http.Server{
+			Addr:              cfg.Server.Addr,
+			Handler:           router,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		},
+		shutdownTimeout: cfg.Server.ShutdownTimeout,
+	}
+
 	// Регистрируем маршруты
-	registerRoutes(router)
+	if err := registerRoutes(s, cfg); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OnShutdown регистрирует хук остановки. Хуки вызываются в обратном порядке
+// регистрации, как defer, и делят общий дедлайн остановки.
+func (s *Server) OnShutdown(hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return &Server{Router: router}
+	s.hooks = append(s.hooks, hook)
 }
 
-// Run запускает сервер
-func (s *Server) Run(port string) {
-	log.Printf("Сервер запущен на :%s", port)
-	if err := s.Router.Run(":" + port); err != nil {
-		log.Fatal("Ошибка запуска сервера:", err)
+// Run запускает сервер и блокируется до отмены ctx. После отмены сервер перестает
+// принимать соединения и ждет завершения обрабатываемых запросов не дольше
+// shutdownTimeout, затем вызывает хуки остановки.
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Infof("Сервер запущен на %s", s.httpServer.Addr)
+		errCh <- s.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Infof("Остановка сервера, ожидание активных запросов до %s", s.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](shutdownCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
 	}
+	logger.Info("Сервер остановлен")
+	return nil
 }
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestServer builds a Server around router on a free local port
+func newTestServer(t *testing.T, router *gin.Engine, shutdownTimeout time.Duration) (*Server, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := &Server{
+		Router:          router,
+		httpServer:      &http.Server{Addr: addr, Handler: router},
+		shutdownTimeout: shutdownTimeout,
+	}
+	return s, "http://" + addr
+}
+
+// start runs s until the returned cancel is called and waits until it accepts connections
+func start(t *testing.T, s *Server, url string) (context.CancelFunc, <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		resp, err := http.Get(url + "/ping")
+		if err == nil {
+			resp.Body.Close()
+			return cancel, done
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newRouter(slow gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	router.GET("/slow", slow)
+	return router
+}
+
+func TestRunFinishesInFlightRequests(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	s, url := newTestServer(t, newRouter(func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.String(http.StatusOK, "done")
+	}), 5*time.Second)
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	for _, name := range []string{"first", "second", "third"} {
+		s.OnShutdown(func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			if !ok || time.Until(deadline) > 5*time.Second {
+				t.Errorf("hook %s: deadline %v, %v, want within the shutdown timeout", name, deadline, ok)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+			return nil
+		})
+	}
+	cancel, done := start(t, s, url)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			t.Errorf("in-flight request: %v", err)
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-entered
+	cancel()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned %v before the in-flight request finished", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	mu.Lock()
+	if len(calls) > 0 {
+		t.Errorf("hooks %v ran before the in-flight request finished", calls)
+	}
+	mu.Unlock()
+	if _, err := http.Get(url + "/ping"); err == nil {
+		t.Error("the server accepted a new connection while shutting down")
+	}
+
+	close(release)
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("in-flight request: status %d, want 200", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+	if want := []string{"third", "second", "first"}; !slices.Equal(calls, want) {
+		t.Errorf("hooks ran as %v, want %v", calls, want)
+	}
+}
+
+func TestRunGivesUpAfterShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	s, url := newTestServer(t, newRouter(func(c *gin.Context) {
+		close(entered)
+		<-release
+	}), 50*time.Millisecond)
+
+	hookRan := make(chan struct{}, 1)
+	s.OnShutdown(func(context.Context) error {
+		hookRan <- struct{}{}
+		return nil
+	})
+	cancel, done := start(t, s, url)
+
+	go func() {
+		if resp, err := http.Get(url + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Run: got %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the shutdown timeout")
+	}
+	select {
+	case <-hookRan:
+	default:
+		t.Error("hooks were skipped after the shutdown timeout")
+	}
+}
+
+func TestRunJoinsHookErrors(t *testing.T) {
+	s, url := newTestServer(t, newRouter(func(*gin.Context) {}), 5*time.Second)
+	errFirst := errors.New("first failed")
+	errSecond := errors.New("second failed")
+	ran := false
+	s.OnShutdown(func(context.Context) error { return errFirst })
+	s.OnShutdown(func(context.Context) error { return errSecond })
+	s.OnShutdown(func(context.Context) error {
+		ran = true
+		return nil
+	})
+	cancel, done := start(t, s, url)
+
+	cancel()
+	err := <-done
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Run: got %v, want both hook errors", err)
+	}
+	if !ran {
+		t.Error("a hook was skipped")
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/sessionstore"
+)
+
+// newSessionStore создает серверное хранилище сессий. В cookie хранится только
+// идентификатор сессии, подписанный ключами из cfg.Secrets.
+func newSessionStore(cfg config.SessionConfig) (sessions.Store, error) {
+	var backend sessionstore.Backend
+	switch cfg.Backend {
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "cover-letter-sessions")
+		}
+		fileBackend, err := sessionstore.NewFileBackend(dir)
+		if err != nil {
+			return nil, err
+		}
+		backend = fileBackend
+	default:
+		backend = sessionstore.NewMemoryBackend()
+	}
+
+	// Первый секрет подписывает новые cookie, остальные принимаются до завершения ротации
+	secrets := cfg.Secrets
+	if len(secrets) == 0 {
+		secret, err := helpers.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		logger.Error("SESSION_SECRETS is not set, using a random key: sessions will not survive a restart")
+		secrets = []string{secret}
+	}
+
+	store, err := sessionstore.NewStore(backend, secrets...)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Options(sessions.Options{
+		Path:     "/",                       // Доступность для всех путей
+		Domain:   "",                        // Пусто = текущий домен
+		MaxAge:   int(cfg.MaxAge.Seconds()), // Время жизни в секундах
+		Secure:   cfg.Secure,                // true для HTTPS только
+		HttpOnly: true,                      // Запрет доступа из JavaScript
+		SameSite: http.SameSiteLaxMode,
+	})
+	return store, nil
+}
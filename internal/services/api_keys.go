@@ -0,0 +1,90 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+)
+
+// apiKeyPrefix отличает наши ключи от токенов hh.ru
+const apiKeyPrefix = "clg_"
+
+// ErrInvalidAPIKey возвращается для неизвестного или отозванного ключа
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyService выдает, проверяет и отзывает API-ключи пользователей
+type APIKeyService struct {
+	store storage.APIKeyStore
+}
+
+// NewAPIKeyService создает новый APIKeyService
+func NewAPIKeyService(store storage.APIKeyStore) *APIKeyService {
+	return &APIKeyService{store: store}
+}
+
+// Issue создает ключ для пользователя. Открытый ключ возвращается только здесь.
+func (s *APIKeyService) Issue(userID, name string) (string, *models.APIKey, error) {
+	secret, err := helpers.RandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := helpers.RandomToken(9)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plain := apiKeyPrefix + secret
+	key := &models.APIKey{
+		ID:        id,
+		Name:      name,
+		UserID:    userID,
+		Hash:      hashAPIKey(plain),
+		Prefix:    plain[:len(apiKeyPrefix)+6],
+		CreatedAt: time.Now(),
+	}
+	if err := s.store.Save(key); err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// Authenticate находит ключ по его открытому значению
+func (s *APIKeyService) Authenticate(plain string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.store.GetByHash(hashAPIKey(plain))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.MarkUsed(key.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// List возвращает ключи пользователя
+func (s *APIKeyService) List(userID string) ([]models.APIKey, error) {
+	return s.store.ListByUser(userID)
+}
+
+// Revoke отзывает ключ пользователя
+func (s *APIKeyService) Revoke(userID, id string) error {
+	return s.store.Delete(userID, id)
+}
+
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker перестает пускать запросы к провайдеру после failures ошибок
+// подряд. Через cooldown пропускается один пробный запрос: успех закрывает
+// выключатель, ошибка снова открывает его на cooldown.
+type CircuitBreaker struct {
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	errors    int       // Ошибок подряд
+	openUntil time.Time // Пока не наступит, запросы не пропускаются
+	probing   bool      // Пробный запрос уже отправлен
+}
+
+// NewCircuitBreaker создает новый CircuitBreaker
+func NewCircuitBreaker(failures int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{failures: failures, cooldown: cooldown, now: time.Now}
+}
+
+// Allow сообщает, можно ли отправить запрос
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.errors < b.failures {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success отмечает успешный запрос
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.errors = 0
+	b.probing = false
+}
+
+// Failure отмечает неудачный запрос
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.errors++
+	b.probing = false
+	if b.errors >= b.failures {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// Release снимает отметку пробного запроса, если он прерван не по вине
+// провайдера, например клиент ушел
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// errStepTimeout — звено цепочки не начало отвечать за свой тайм-аут
+var errStepTimeout = errors.New("llm provider timed out")
+
+// FallbackStep — провайдер в цепочке FallbackProvider
+type FallbackStep struct {
+	Name     string
+	Provider LLMProvider
+	Timeout  time.Duration // 0 — только тайм-аут самого провайдера
+}
+
+type fallbackStep struct {
+	FallbackStep
+	breaker *CircuitBreaker
+}
+
+// FallbackProvider пробует провайдеров по очереди, пока один не ответит.
+// Провайдер, который часто падает, пропускается выключателем. Имя
+// ответившего провайдера записывается в контекст, см. TrackProvider.
+type FallbackProvider struct {
+	steps []fallbackStep
+}
+
+// NewFallbackProvider создает FallbackProvider. failures и cooldown задают
+// выключатель каждого провайдера.
+func NewFallbackProvider(steps []FallbackStep, failures int, cooldown time.Duration) *FallbackProvider {
+	f := &FallbackProvider{}
+	for _, step := range steps {
+		f.steps = append(f.steps, fallbackStep{FallbackStep: step, breaker: NewCircuitBreaker(failures, cooldown)})
+	}
+	return f
+}
+
+// run вызывает call у провайдеров по очереди. Если вызывающий отменил
+// запрос или провайдер отверг сам запрос, цепочка прерывается без штрафа
+// провайдеру. started сообщает, что провайдер уже начал отвечать и переходить
+// к следующему поздно.
+func (f *FallbackProvider) run(ctx context.Context, call func(ctx context.Context, step fallbackStep, started func()) error) error {
+	var errs []error
+	for _, step := range f.steps {
+		if !step.breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: skipped, circuit open", step.Name))
+			continue
+		}
+
+		stepCtx, cancel := context.WithCancelCause(ctx)
+		stopTimer := func() bool { return false }
+		if step.Timeout > 0 {
+			stopTimer = time.AfterFunc(step.Timeout, func() { cancel(errStepTimeout) }).Stop
+		}
+		begun := false
+		started := func() {
+			if !begun {
+				begun = true
+				stopTimer()
+			}
+		}
+
+		err := call(stepCtx, step, started)
+		stopTimer()
+		if err != nil && errors.Is(context.Cause(stepCtx), errStepTimeout) && ctx.Err() == nil {
+			err = clients.NewError(clients.KindUpstreamUnavailable, "llm_timeout",
+				fmt.Sprintf("no answer within %s", step.Timeout))
+		}
+		cancel(nil)
+
+		switch {
+		case err == nil:
+			step.breaker.Success()
+			recordProvider(ctx, step.Name)
+			return nil
+		case ctx.Err() != nil:
+			step.breaker.Release()
+			return ctx.Err()
+		case !transient(err):
+			// Следующий провайдер отверг бы этот запрос так же
+			step.breaker.Release()
+			return err
+		}
+
+		step.breaker.Failure()
+		if begun {
+			// Клиент уже получил часть текста от этого провайдера
+			return err
+		}
+		logger.Warnf("LLM provider %s failed, trying the next one: %v", step.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+	}
+
+	return &clients.Error{
+		Kind:    clients.KindUpstreamUnavailable,
+		Code:    "llm_unavailable",
+		Message: "all LLM providers failed: " + strings.Join(f.names(), ", "),
+		Err:     errors.Join(errs...),
+	}
+}
+
+// transient сообщает, что ошибка — сбой провайдера, а не запроса: тайм-аут,
+// ответ 5xx или 429, сетевая ошибка. Только такие ошибки открывают выключатель
+// и передают запрос следующему провайдеру.
+func transient(err error) bool {
+	e, ok := clients.AsError(err)
+	if !ok {
+		return true
+	}
+	switch {
+	case e.Kind == clients.KindRateLimited:
+		return true
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return e.StatusCode == http.StatusRequestTimeout
+	default:
+		return e.Kind == clients.KindUpstreamUnavailable
+	}
+}
+
+func (f *FallbackProvider) names() []string {
+	names := make([]string, 0, len(f.steps))
+	for _, step := range f.steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+func (f *FallbackProvider) GenerateCoverLetter(ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error) {
+	var text string
+	err := f.run(ctx, func(ctx context.Context, step fallbackStep, _ func()) (err error) {
+		text, err = step.Provider.GenerateCoverLetter(ctx, resume, vacancy)
+		return err
+	})
+	return text, err
+}
+
+func (f *FallbackProvider) DraftReply(
+	ctx context.Context, thread []models.NegotiationMessage, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error) {
+	var text string
+	err := f.run(ctx, func(ctx context.Context, step fallbackStep, _ func()) (err error) {
+		text, err = step.Provider.DraftReply(ctx, thread, resume, vacancy)
+		return err
+	})
+	return text, err
+}
+
+func (f *FallbackProvider) AnswerTest(
+	ctx context.Context, test *models.Test, resume *models.ResumeShort, vacancy *models.VacancyShort) ([]models.TestAnswer, error) {
+	var answers []models.TestAnswer
+	err := f.run(ctx, func(ctx context.Context, step fallbackStep, _ func()) (err error) {
+		answers, err = step.Provider.AnswerTest(ctx, test, resume, vacancy)
+		return err
+	})
+	return answers, err
+}
+
+// StreamCoverLetter переходит к следующему провайдеру, только пока клиент не
+// получил ни одного фрагмента. Тайм-аут звена здесь — время до первого фрагмента.
+func (f *FallbackProvider) StreamCoverLetter(
+	ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort, onDelta func(string) error) (string, error) {
+	var text string
+	err := f.run(ctx, func(ctx context.Context, step fallbackStep, started func()) (err error) {
+		streamer, ok := step.Provider.(CoverLetterStreamer)
+		if !ok {
+			if text, err = step.Provider.GenerateCoverLetter(ctx, resume, vacancy); err != nil {
+				return err
+			}
+			started()
+			return onDelta(text)
+		}
+		text, err = streamer.StreamCoverLetter(ctx, resume, vacancy, func(delta string) error {
+			started()
+			return onDelta(delta)
+		})
+		return err
+	})
+	return text, err
+}
+
+type providerKey struct{}
+
+// providerRecord — куда FallbackProvider записывает ответившего провайдера
+type providerRecord struct {
+	mu   sync.Mutex
+	name string
+}
+
+// TrackProvider возвращает контекст, в котором FallbackProvider отметит
+// провайдера, написавшего ответ, и функцию, возвращающую его имя. Для
+// обычного провайдера имя остается пустым.
+func TrackProvider(ctx context.Context) (context.Context, func() string) {
+	record := &providerRecord{}
+	return context.WithValue(ctx, providerKey{}, record), func() string {
+		record.mu.Lock()
+		defer record.mu.Unlock()
+		return record.name
+	}
+}
+
+func recordProvider(ctx context.Context, name string) {
+	record, ok := ctx.Value(providerKey{}).(*providerRecord)
+	if !ok {
+		return
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	// Внешняя цепочка не должна затирать имя из вложенной
+	if record.name == "" {
+		record.name = name
+	}
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+
 	"github.com/rustamnr/cover-letter-generator/internal/clients"
 	"github.com/rustamnr/cover-letter-generator/internal/models"
 )
@@ -13,30 +15,65 @@ func NewHHProvider(client *clients.HHClient) *HHProvider {
 	return &HHProvider{client: client}
 }
 
-func (h *HHProvider) GetResumeByID(resumeID string) (*models.Resume, error) {
-	return h.client.GetResume(resumeID)
+func (h *HHProvider) GetResumeByID(ctx context.Context, resumeID string) (*models.Resume, error) {
+	return h.client.GetResume(ctx, resumeID)
+}
+
+func (h *HHProvider) GetShortResumeByID(ctx context.Context, resumeID string) (*models.ResumeShort, error) {
+	return h.client.GetShortResume(ctx, resumeID)
+}
+
+func (h *HHProvider) GetVacancyByID(ctx context.Context, vacancyID string) (*models.Vacancy, error) {
+	return h.client.GetVacancyByID(ctx, vacancyID)
 }
 
-func (h *HHProvider) GetShortResumeByID(resumeID string) (*models.ResumeShort, error) {
-	return h.client.GetShortResume(resumeID)
+func (h *HHProvider) GetShortVacancyByID(ctx context.Context, vacancyID string) (*models.VacancyShort, error) {
+	return h.client.GetShortVacancyByID(ctx, vacancyID)
 }
 
-func (h *HHProvider) GetVacancyByID(vacancyID string) (*models.Vacancy, error) {
-	return h.client.GetVacancyByID(vacancyID)
+func (h *HHProvider) GetFirstShortSuitableVacancy(ctx context.Context, resumeID string) (*models.VacancyShort, error) {
+	return h.client.GetFirstShortSuitableVacancy(ctx, resumeID)
 }
 
-func (h *HHProvider) GetShortVacancyByID(vacancyID string) (*models.VacancyShort, error) {
-	return h.client.GetShortVacancyByID(vacancyID)
+func (h *HHProvider) ApplyToVacancy(ctx context.Context, resumeID, vacancyID, coverLetter string) error {
+	return h.client.PostNegotiationByVacancyID(ctx, resumeID, vacancyID, coverLetter)
 }
 
-func (h *HHProvider) GetFirstShortSuitableVacancy(resumeID string) (*models.VacancyShort, error) {
-	return h.client.GetFirstShortSuitableVacancy(resumeID)
+func (h *HHProvider) GetVacancyTest(ctx context.Context, vacancyID string) (*models.Test, error) {
+	return h.client.GetVacancyTest(ctx, vacancyID)
 }
 
-func (h *HHProvider) ApplyToVacancy(resumeID, vacancyID, coverLetter string) error {
-	return h.client.PostNegotiationByVacancyID(resumeID, vacancyID, coverLetter)
+func (h *HHProvider) GetNegotiation(ctx context.Context, nid string) (*models.Negotiation, error) {
+	return h.client.GetNegotiation(ctx, nid)
+}
+
+// threadPageSize — сколько последних сообщений переписки передается в LLM
+const threadPageSize = 100
+
+// GetNegotiationThread возвращает последние threadPageSize сообщений. Сообщения
+// идут от старых к новым, а последняя страница может быть почти пустой, поэтому
+// для длинной переписки берутся две последние страницы.
+func (h *HHProvider) GetNegotiationThread(ctx context.Context, nid string) ([]models.NegotiationMessage, error) {
+	messages, err := h.client.GetNegotiationMessages(ctx, nid, 0, threadPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if messages.Pages <= 1 {
+		return messages.Items, nil
+	}
+
+	var thread []models.NegotiationMessage
+	for page := max(messages.Pages-2, 0); page < messages.Pages; page++ {
+		if page > 0 {
+			if messages, err = h.client.GetNegotiationMessages(ctx, nid, page, threadPageSize); err != nil {
+				return nil, err
+			}
+		}
+		thread = append(thread, messages.Items...)
+	}
+	return thread[max(len(thread)-threadPageSize, 0):], nil
 }
 
-func (h *HHProvider) SetAccessToken(token string) {
-	h.client.SetAccessToken(token)
+func (h *HHProvider) WithToken(userID string, token *models.HHToken) JobAgregatorProvider {
+	return &HHProvider{client: h.client.WithToken(userID, token)}
 }
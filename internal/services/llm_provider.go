@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/pkg/promts"
+)
+
+// LLMService реализует LLMProvider поверх любого клиента языковой модели:
+// промпты одинаковы для всех провайдеров, различается только транспорт
+type LLMService struct {
+	client    clients.LLMClient
+	maxTokens int
+}
+
+// NewLLMService создает новый экземпляр LLMService
+func NewLLMService(client clients.LLMClient, maxTokens int) *LLMService {
+	return &LLMService{
+		client:    client,
+		maxTokens: maxTokens,
+	}
+}
+
+// GenerateCoverLetter пишет сопроводительное письмо к вакансии по резюме
+func (s *LLMService) GenerateCoverLetter(ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error) {
+	return s.client.SendPromt(ctx, s.coverLetterRequest(resume, vacancy))
+}
+
+// StreamCoverLetter пишет письмо, передавая onDelta текст по мере генерации.
+// Если клиент не умеет отдавать ответ потоком, письмо приходит одним фрагментом.
+func (s *LLMService) StreamCoverLetter(
+	ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort, onDelta func(string) error) (string, error) {
+	request := s.coverLetterRequest(resume, vacancy)
+
+	streamer, ok := s.client.(clients.LLMStreamClient)
+	if !ok {
+		text, err := s.client.SendPromt(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		return text, onDelta(text)
+	}
+
+	var text strings.Builder
+	usage, err := streamer.StreamPromt(ctx, request, func(delta string) error {
+		text.WriteString(delta)
+		return onDelta(delta)
+	})
+	if err != nil {
+		return "", err
+	}
+	if usage != nil {
+		logger.Debugf("LLM stream usage: %d prompt, %d completion tokens", usage.PromptTokens, usage.CompletionTokens)
+	}
+	if text.Len() == 0 {
+		return "", clients.NewError(clients.KindUpstreamUnavailable, "empty_completion", "response contains no text")
+	}
+	return text.String(), nil
+}
+
+func (s *LLMService) coverLetterRequest(resume *models.ResumeShort, vacancy *models.VacancyShort) clients.LLMRequest {
+	content := fmt.Sprint(resume.ToString(), vacancy.ToString())
+	logger.Debugf("LLM request content: %s", content)
+	return clients.LLMRequest{
+		System:    promts.CoverLetterSystemContext,
+		Content:   content,
+		MaxTokens: s.maxTokens,
+	}
+}
+
+// DraftReply предлагает ответ работодателю. Промпт запрещает придумывать факты,
+// которых нет в резюме, вместо них модель оставляет пометки для кандидата.
+func (s *LLMService) DraftReply(
+	ctx context.Context, thread []models.NegotiationMessage, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error) {
+	content := fmt.Sprint(resume.ToString(), vacancy.ToString(), "\nПереписка:\n", models.ThreadToString(thread))
+	logger.Debugf("LLM request content: %s", content)
+	request := clients.LLMRequest{
+		System:    promts.NegotiationReplySystemContext,
+		Content:   content,
+		MaxTokens: s.maxTokens,
+	}
+
+	return s.client.SendPromt(ctx, request)
+}
+
+// AnswerTest предлагает ответы на анкету работодателя. Ответы на неизвестные
+// вопросы и несуществующие варианты отбрасываются.
+func (s *LLMService) AnswerTest(
+	ctx context.Context, test *models.Test, resume *models.ResumeShort, vacancy *models.VacancyShort) ([]models.TestAnswer, error) {
+	content := fmt.Sprint(resume.ToString(), vacancy.ToString(), "\n", test.ToString())
+	logger.Debugf("LLM request content: %s", content)
+	request := clients.LLMRequest{
+		System:    promts.QuestionnaireSystemContext,
+		Content:   content,
+		MaxTokens: s.maxTokens,
+	}
+
+	answer, err := s.client.SendPromt(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTestAnswers(answer, test)
+}
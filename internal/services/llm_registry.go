@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+)
+
+// LLMRegistry хранит провайдеров языковых моделей по именам. Заполняется при
+// запуске и дальше только читается, поэтому блокировки не нужны.
+type LLMRegistry struct {
+	providers   map[string]LLMProvider
+	names       []string
+	defaultName string
+}
+
+// NewLLMRegistry создает пустой LLMRegistry
+func NewLLMRegistry() *LLMRegistry {
+	return &LLMRegistry{providers: make(map[string]LLMProvider)}
+}
+
+// Register добавляет провайдера. Первый добавленный становится провайдером
+// по умолчанию, пока не вызван SetDefault.
+func (r *LLMRegistry) Register(name string, provider LLMProvider) {
+	if _, ok := r.providers[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.providers[name] = provider
+	if r.defaultName == "" {
+		r.defaultName = name
+	}
+}
+
+// SetDefault выбирает провайдера по умолчанию среди добавленных
+func (r *LLMRegistry) SetDefault(name string) error {
+	if _, ok := r.providers[name]; !ok {
+		return r.unknown(name)
+	}
+	r.defaultName = name
+	return nil
+}
+
+// Default возвращает имя провайдера по умолчанию
+func (r *LLMRegistry) Default() string {
+	return r.defaultName
+}
+
+// Names возвращает имена провайдеров в порядке добавления
+func (r *LLMRegistry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Has сообщает, добавлен ли провайдер
+func (r *LLMRegistry) Has(name string) bool {
+	_, ok := r.providers[name]
+	return ok
+}
+
+// Get возвращает провайдера по имени, а для пустого имени — по умолчанию
+func (r *LLMRegistry) Get(name string) (LLMProvider, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, r.unknown(name)
+	}
+	return provider, nil
+}
+
+func (r *LLMRegistry) unknown(name string) error {
+	if len(r.names) == 0 {
+		return clients.NewError(clients.KindUpstreamUnavailable, "llm_not_configured", "no LLM provider is configured")
+	}
+	return clients.NewError(clients.KindValidationFailed, "unknown_llm_provider",
+		fmt.Sprintf("unknown LLM provider %q, available: %s", name, strings.Join(r.names, ", ")))
+}
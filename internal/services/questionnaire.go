@@ -0,0 +1,58 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// parseTestAnswers разбирает JSON-ответ модели на анкету. Модели иногда
+// оборачивают JSON в блок ```json, его снимаем.
+func parseTestAnswers(answer string, test *models.Test) ([]models.TestAnswer, error) {
+	answer = strings.TrimSpace(answer)
+	answer = strings.TrimPrefix(answer, "```json")
+	answer = strings.TrimPrefix(answer, "```")
+	answer = strings.TrimSuffix(answer, "```")
+
+	var proposed []models.TestAnswer
+	if err := json.Unmarshal([]byte(strings.TrimSpace(answer)), &proposed); err != nil {
+		return nil, &clients.Error{
+			Kind:    clients.KindUpstreamUnavailable,
+			Code:    "invalid_llm_answer",
+			Message: "the model returned answers in an unexpected format, try again",
+			Err:     err,
+		}
+	}
+
+	questions := make(map[string]models.TestQuestion, len(test.Questions))
+	for _, question := range test.Questions {
+		questions[question.ID] = question
+	}
+
+	answers := make([]models.TestAnswer, 0, len(proposed))
+	for _, answer := range proposed {
+		question, ok := questions[answer.QuestionID]
+		if !ok {
+			continue
+		}
+
+		if question.Type == models.QuestionText {
+			answer.OptionIDs = nil
+		} else {
+			answer.Text = ""
+			answer.OptionIDs = slices.DeleteFunc(answer.OptionIDs, func(id string) bool {
+				return !slices.ContainsFunc(question.Options, func(o models.TestOption) bool { return o.ID == id })
+			})
+			if question.Type == models.QuestionSingle && len(answer.OptionIDs) > 1 {
+				answer.OptionIDs = answer.OptionIDs[:1]
+			}
+		}
+
+		answers = append(answers, answer)
+	}
+
+	return answers, nil
+}
@@ -1,35 +1,53 @@
 package services
 
 import (
+	"context"
+
 	"github.com/rustamnr/cover-letter-generator/internal/models"
 )
 
 // JobAgregatorProvider определяет методы для работы с агрегаторами вакансий
 type JobAgregatorProvider interface {
-	GetResumeByID(resumeID string) (*models.Resume, error)
-	GetShortResumeByID(resumeID string) (*models.ResumeShort, error)
-	GetVacancyByID(vacancyID string) (*models.Vacancy, error)
-	GetShortVacancyByID(vacancyID string) (*models.VacancyShort, error)
-	GetFirstShortSuitableVacancy(resumeID string) (*models.VacancyShort, error)
-	ApplyToVacancy(resumeID, vacancyID, coverLetter string) error
-	SetAccessToken(token string)
+	GetResumeByID(ctx context.Context, resumeID string) (*models.Resume, error)
+	GetShortResumeByID(ctx context.Context, resumeID string) (*models.ResumeShort, error)
+	GetVacancyByID(ctx context.Context, vacancyID string) (*models.Vacancy, error)
+	GetShortVacancyByID(ctx context.Context, vacancyID string) (*models.VacancyShort, error)
+	GetFirstShortSuitableVacancy(ctx context.Context, resumeID string) (*models.VacancyShort, error)
+	ApplyToVacancy(ctx context.Context, resumeID, vacancyID, coverLetter string) error
+	GetVacancyTest(ctx context.Context, vacancyID string) (*models.Test, error)
+	GetNegotiation(ctx context.Context, nid string) (*models.Negotiation, error)
+	// GetNegotiationThread возвращает последние сообщения переписки по отклику
+	GetNegotiationThread(ctx context.Context, nid string) ([]models.NegotiationMessage, error)
+	// WithToken возвращает провайдера, работающего от имени пользователя.
+	// Исходный провайдер не изменяется, поэтому его можно разделять между запросами.
+	WithToken(userID string, token *models.HHToken) JobAgregatorProvider
 }
 
 // LLMProvider определяет методы для работы с генераторами текста
 type LLMProvider interface {
-	GenerateCoverLetter(resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error)
+	GenerateCoverLetter(ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error)
+	// DraftReply предлагает ответ работодателю на основе переписки, резюме и вакансии
+	DraftReply(ctx context.Context, thread []models.NegotiationMessage, resume *models.ResumeShort, vacancy *models.VacancyShort) (string, error)
+	// AnswerTest предлагает ответы на анкету работодателя на основе резюме
+	AnswerTest(ctx context.Context, test *models.Test, resume *models.ResumeShort, vacancy *models.VacancyShort) ([]models.TestAnswer, error)
+}
+
+// CoverLetterStreamer — LLMProvider, который отдает письмо по частям.
+// onDelta получает очередной фрагмент; ошибка onDelta прерывает генерацию.
+type CoverLetterStreamer interface {
+	StreamCoverLetter(ctx context.Context, resume *models.ResumeShort, vacancy *models.VacancyShort, onDelta func(string) error) (string, error)
 }
 
 // ApplicationService объединяет работу с вакансиями и генерацией текста
 type ApplicationService struct {
 	VacancyProvider JobAgregatorProvider
-	TextGenerator   LLMProvider
+	TextGenerators  *LLMRegistry // Провайдер выбирается на каждый запрос
 }
 
 // NewApplicationService создает новый ApplicationService
-func NewApplicationService(vacancyProvider JobAgregatorProvider, textGenerator LLMProvider) *ApplicationService {
+func NewApplicationService(vacancyProvider JobAgregatorProvider, textGenerators *LLMRegistry) *ApplicationService {
 	return &ApplicationService{
 		VacancyProvider: vacancyProvider,
-		TextGenerator:   textGenerator,
+		TextGenerators:  textGenerators,
 	}
 }
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/clients"
+	"github.com/rustamnr/cover-letter-generator/internal/config"
+	"github.com/rustamnr/cover-letter-generator/internal/helpers"
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+	"github.com/rustamnr/cover-letter-generator/internal/storage"
+)
+
+// pruneInterval — как часто UsageService удаляет расход старше хранимых месяцев
+const pruneInterval = time.Hour
+
+// UsageService считает стоимость генераций по таблице цен моделей и следит
+// за месячными бюджетами пользователей. Месяц считается по UTC.
+type UsageService struct {
+	store       storage.UsageStore
+	prices      map[string]config.LLMPrice
+	budget      float64
+	userBudgets map[string]float64
+	months      int
+	now         func() time.Time
+
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+}
+
+// NewUsageService создает новый UsageService
+func NewUsageService(store storage.UsageStore, cfg config.LLMUsageConfig) (*UsageService, error) {
+	prices, err := cfg.PriceTable()
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := cfg.Budgets()
+	if err != nil {
+		return nil, err
+	}
+	s := &UsageService{
+		store:       store,
+		prices:      prices,
+		budget:      cfg.MonthlyBudget,
+		userBudgets: budgets,
+		months:      max(cfg.Months, 1),
+		now:         time.Now,
+	}
+	s.prune()
+	return s, nil
+}
+
+// monthStart возвращает начало месяца, в который попадает t
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// prune удаляет расход за месяцы, которые уже не хранятся, не чаще раза в pruneInterval
+func (s *UsageService) prune() {
+	s.pruneMu.Lock()
+	defer s.pruneMu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	before := monthStart(now).AddDate(0, 1-s.months, 0)
+	if err := s.store.Prune(before); err != nil {
+		logger.Errorf("failed to prune LLM usage before %s: %v", before.Format("2006-01"), err)
+	}
+}
+
+// price считает стоимость ответа модели
+func (s *UsageService) price(usage clients.LLMUsage) models.TokenUsage {
+	cost := 0.0
+	if price, ok := s.prices[usage.Model]; ok {
+		cost = (float64(usage.PromptTokens-usage.CacheHitTokens)*price.Prompt +
+			float64(usage.CacheHitTokens)*price.CacheHit +
+			float64(usage.CompletionTokens)*price.Completion) / 1e6
+	}
+	return models.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CacheHitTokens:   usage.CacheHitTokens,
+		Cost:             cost,
+	}
+}
+
+// Record сохраняет расход генерации. Заполняет ID, модель, токены и время;
+// без ответов с расходом возвращает nil: провайдер не ответил или не сообщил его.
+func (s *UsageService) Record(generation *models.GenerationUsage, usage []clients.LLMUsage) (*models.GenerationUsage, error) {
+	if len(usage) == 0 {
+		return nil, nil
+	}
+	s.prune()
+
+	id, err := helpers.RandomToken(9)
+	if err != nil {
+		return nil, err
+	}
+	generation.ID = id
+	generation.CreatedAt = s.now()
+	for _, u := range usage {
+		generation.TokenUsage.Add(s.price(u))
+		generation.Model = u.Model
+	}
+
+	if err := s.store.Add(generation); err != nil {
+		return nil, err
+	}
+	return generation, nil
+}
+
+// Budget возвращает месячный бюджет пользователя, 0 — без ограничения
+func (s *UsageService) Budget(userID string) float64 {
+	if budget, ok := s.userBudgets[userID]; ok {
+		return budget
+	}
+	return s.budget
+}
+
+// CheckBudget не дает начать генерацию, если пользователь израсходовал
+// месячный бюджет. Параллельные генерации могут превысить его на свою стоимость.
+func (s *UsageService) CheckBudget(userID string) error {
+	budget := s.Budget(userID)
+	if budget <= 0 {
+		return nil
+	}
+
+	summary, err := s.Summary(userID, s.now())
+	if err != nil {
+		return err
+	}
+	if summary.Exceeded {
+		return clients.NewError(clients.KindRateLimited, "llm_budget_exceeded",
+			fmt.Sprintf("monthly LLM budget of $%g is spent", budget))
+	}
+	return nil
+}
+
+// Summary возвращает расход пользователя за месяц, в который попадает month
+func (s *UsageService) Summary(userID string, month time.Time) (*models.UsageSummary, error) {
+	from := monthStart(month)
+	generations, err := s.store.List(userID, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.UsageSummary{
+		Month:       from.Format("2006-01"),
+		Budget:      s.Budget(userID),
+		Generations: generations,
+	}
+	if summary.Generations == nil {
+		summary.Generations = []models.GenerationUsage{}
+	}
+	for _, generation := range generations {
+		summary.Total.Add(generation.TokenUsage)
+	}
+	summary.Exceeded = summary.Budget > 0 && summary.Total.Cost >= summary.Budget
+	return summary, nil
+}
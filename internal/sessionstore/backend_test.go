@@ -0,0 +1,193 @@
+package sessionstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackends(t *testing.T) {
+	for name, open := range map[string]func(t *testing.T) Backend{
+		"memory": func(*testing.T) Backend { return NewMemoryBackend() },
+		"file": func(t *testing.T) Backend {
+			b, err := NewFileBackend(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := open(t)
+			if _, err := b.Load("ABC"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Load of a missing session: %v, want ErrNotFound", err)
+			}
+
+			if err := b.Save("ABC", []byte("first"), time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Save("ABC", []byte("second"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if data, err := b.Load("ABC"); err != nil || string(data) != "second" {
+				t.Errorf("Load = %q, %v, want the last saved data", data, err)
+			}
+
+			if err := b.Delete("ABC"); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Delete("ABC"); err != nil {
+				t.Errorf("second Delete: %v", err)
+			}
+			if _, err := b.Load("ABC"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Load after Delete: %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMemoryBackendExpiry(t *testing.T) {
+	b := NewMemoryBackend()
+	if err := b.Save("SHORT", []byte("data"), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save("FOREVER", []byte("data"), 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := b.Load("SHORT"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load of an expired session: %v, want ErrNotFound", err)
+	}
+	if _, err := b.Load("FOREVER"); err != nil {
+		t.Errorf("Load of a session without ttl: %v", err)
+	}
+}
+
+func TestFileBackendExpiry(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file format keeps whole seconds, so write an already expired session directly
+	content := make([]byte, 8)
+	binary.BigEndian.PutUint64(content, uint64(time.Now().Add(-time.Minute).Unix()))
+	path := filepath.Join(dir, sessionFilePrefix+"OLD")
+	if err := os.WriteFile(path, append(content, "data"...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Load("OLD"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load of an expired session: %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the expired session file was not removed: %v", err)
+	}
+}
+
+func TestFileBackendSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save("ABC", []byte("data"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := reopened.Load("ABC"); err != nil || string(data) != "data" {
+		t.Errorf("Load after restart = %q, %v", data, err)
+	}
+}
+
+func TestFileBackendWritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small := []byte("small")
+	large := bytes.Repeat([]byte("large"), 64<<10)
+	if err := b.Save("ABC", small, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			data := small
+			if i%2 == 0 {
+				data = large
+			}
+			if err := b.Save("ABC", data, time.Hour); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			data, err := b.Load("ABC")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(data, small) && !bytes.Equal(data, large) {
+				t.Errorf("read a partly written session of %d bytes", len(data))
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".tmp_") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, sessionFilePrefix+"ABC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("session file mode = %o, want 600", mode)
+	}
+}
+
+func TestFileBackendRejectsPathsInIDs(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(filepath.Join(dir, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "../ABC", "abc", "A/B", "ABC1"} {
+		if err := b.Save(id, []byte("data"), time.Hour); err == nil {
+			t.Errorf("Save(%q) succeeded", id)
+		}
+		if _, err := b.Load(id); err == nil {
+			t.Errorf("Load(%q) succeeded", id)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("files outside the session directory: %v", entries)
+	}
+}
@@ -0,0 +1,154 @@
+package sessionstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionFilePrefix = "session_"
+
+// FileBackend хранит каждую сессию в отдельном файле каталога dir.
+// Первые 8 байт файла — время истечения сессии в формате Unix.
+type FileBackend struct {
+	dir       string
+	mu        sync.RWMutex
+	sweepMu   sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileBackend создает FileBackend, при необходимости создавая каталог
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+func (b *FileBackend) Load(id string) ([]byte, error) {
+	path, err := b.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	content, err := os.ReadFile(path)
+	b.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	at, data, err := decodeSessionFile(content)
+	if err != nil {
+		return nil, err
+	}
+	if expired(at) {
+		_ = b.Delete(id)
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (b *FileBackend) Save(id string, data []byte, ttl time.Duration) error {
+	path, err := b.path(id)
+	if err != nil {
+		return err
+	}
+
+	b.sweep()
+
+	content := make([]byte, 8, 8+len(data))
+	if at := expiresAt(ttl); !at.IsZero() {
+		binary.BigEndian.PutUint64(content, uint64(at.Unix()))
+	}
+	content = append(content, data...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Пишем во временный файл и переименовываем, чтобы не читать недописанную сессию
+	tmp, err := os.CreateTemp(b.dir, ".tmp_"+sessionFilePrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *FileBackend) Delete(id string) error {
+	path, err := b.path(id)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path возвращает путь к файлу сессии. Идентификатор приходит из подписанной
+// cookie, но проверяется еще раз, чтобы исключить выход за пределы каталога.
+func (b *FileBackend) path(id string) (string, error) {
+	if id == "" || strings.ContainsFunc(id, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '2' && r <= '7')
+	}) {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(b.dir, sessionFilePrefix+id), nil
+}
+
+// sweep удаляет файлы истекших сессий не чаще раза в sweepInterval
+func (b *FileBackend) sweep() {
+	b.sweepMu.Lock()
+	if time.Since(b.lastSweep) < sweepInterval {
+		b.sweepMu.Unlock()
+		return
+	}
+	b.lastSweep = time.Now()
+	b.sweepMu.Unlock()
+
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), sessionFilePrefix)
+		if !ok {
+			continue
+		}
+		// Load удаляет истекшую сессию
+		_, _ = b.Load(id)
+	}
+}
+
+func decodeSessionFile(content []byte) (time.Time, []byte, error) {
+	if len(content) < 8 {
+		return time.Time{}, nil, errors.New("session file is corrupted")
+	}
+
+	var at time.Time
+	if unix := binary.BigEndian.Uint64(content[:8]); unix != 0 {
+		at = time.Unix(int64(unix), 0)
+	}
+	return at, content[8:], nil
+}
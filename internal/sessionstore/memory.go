@@ -0,0 +1,84 @@
+package sessionstore
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто бэкенды удаляют истекшие сессии
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryBackend хранит сессии в памяти процесса. Сессии теряются при перезапуске.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryBackend создает новый MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]memoryEntry)}
+}
+
+func (b *MemoryBackend) Load(id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if expired(entry.expiresAt) {
+		delete(b.entries, id)
+		return nil, ErrNotFound
+	}
+	return entry.data, nil
+}
+
+func (b *MemoryBackend) Save(id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep()
+	b.entries[id] = memoryEntry{data: data, expiresAt: expiresAt(ttl)}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, id)
+	return nil
+}
+
+// sweep удаляет истекшие сессии не чаще раза в sweepInterval. Вызывается под b.mu.
+func (b *MemoryBackend) sweep() {
+	if time.Since(b.lastSweep) < sweepInterval {
+		return
+	}
+	b.lastSweep = time.Now()
+
+	for id, entry := range b.entries {
+		if expired(entry.expiresAt) {
+			delete(b.entries, id)
+		}
+	}
+}
+
+// expiresAt возвращает момент истечения сессии; нулевое значение — бессрочно
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(at time.Time) bool {
+	return !at.IsZero() && time.Now().After(at)
+}
@@ -0,0 +1,178 @@
+package sessionstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+)
+
+// ErrNotFound возвращается бэкендом, если сессия не найдена или истекла
+var ErrNotFound = errors.New("session not found")
+
+// Backend хранит данные сессий на стороне сервера
+type Backend interface {
+	Load(id string) ([]byte, error)
+	Save(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// Store — хранилище сессий для gin-contrib/sessions. В cookie хранится только
+// подписанный и зашифрованный идентификатор сессии, сами данные — в Backend.
+type Store struct {
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+	backend Backend
+}
+
+var _ sessions.Store = (*Store)(nil)
+
+var idEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// rotateKey помечает сессию, которой Save должен выдать новый идентификатор
+const rotateKey = "_sessionstore_rotate"
+
+// Rotate просит Store при следующем Save выдать сессии новый идентификатор,
+// а данные под старым удалить. Вызывается после входа, чтобы идентификатор,
+// известный до аутентификации, не стал идентификатором вошедшего пользователя.
+func Rotate(session sessions.Session) {
+	session.Set(rotateKey, true)
+}
+
+// NewStore создает хранилище сессий. secrets задают ключи подписи и шифрования
+// cookie: первый секрет используется для новых cookie, остальные только для
+// проверки, что позволяет ротировать ключи без разлогинивания пользователей.
+func NewStore(backend Backend, secrets ...string) (*Store, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("at least one session secret is required")
+	}
+
+	var keyPairs [][]byte
+	for _, secret := range secrets {
+		if secret == "" {
+			return nil, errors.New("session secret must not be empty")
+		}
+		keyPairs = append(keyPairs, deriveKey(secret, "authentication"), deriveKey(secret, "encryption"))
+	}
+
+	s := &Store{
+		codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{Path: "/", MaxAge: 86400 * 30},
+		backend: backend,
+	}
+	s.setMaxAge(s.options.MaxAge)
+	return s, nil
+}
+
+// deriveKey получает 32-байтовый ключ нужного назначения из секрета произвольной длины
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Options задает параметры cookie сессии
+func (s *Store) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	s.setMaxAge(s.options.MaxAge)
+}
+
+func (s *Store) setMaxAge(age int) {
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get возвращает сессию из реестра запроса
+func (s *Store) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New загружает сессию по идентификатору из cookie или создает новую
+func (s *Store) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	if err := securecookie.DecodeMulti(name, cookie.Value, &session.ID, s.codecs...); err != nil {
+		// Cookie подписана неизвестным ключом или истекла — начинаем новую сессию
+		session.ID = ""
+		return session, nil
+	}
+
+	data, err := s.backend.Load(session.ID)
+	if errors.Is(err, ErrNotFound) {
+		// Идентификатор не выдавался или сессия истекла: не принимаем его,
+		// иначе его можно навязать пользователю до входа
+		session.ID = ""
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save сохраняет данные сессии в бэкенд и выставляет cookie с ее идентификатором
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if _, rotate := session.Values[rotateKey]; rotate {
+		delete(session.Values, rotateKey)
+		if session.ID != "" {
+			if err := s.backend.Delete(session.ID); err != nil {
+				return err
+			}
+			session.ID = ""
+		}
+	}
+	if session.ID == "" {
+		session.ID = idEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(session.Values); err != nil {
+		return err
+	}
+
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if err := s.backend.Save(session.ID, data.Bytes(), ttl); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
@@ -0,0 +1,185 @@
+package sessionstore
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+)
+
+const cookieName = "session"
+
+// roundTrip loads the session for a request carrying cookie (if any), lets
+// change modify it and returns the saved session with the cookie it set
+func roundTrip(t *testing.T, s *Store, cookie *http.Cookie, change func(*gsessions.Session)) (*gsessions.Session, *http.Cookie) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	session, err := s.New(r, cookieName)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if change != nil {
+		change(session)
+	}
+
+	w := httptest.NewRecorder()
+	if err := s.Save(r, w, session); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == cookieName {
+			return session, c
+		}
+	}
+	t.Fatal("Save set no session cookie")
+	return nil, nil
+}
+
+// load returns the session the store finds for cookie without saving it
+func load(t *testing.T, s *Store, cookie *http.Cookie) *gsessions.Session {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	session, err := s.New(r, cookieName)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return session
+}
+
+func newStore(t *testing.T, backend Backend, secrets ...string) *Store {
+	t.Helper()
+	s, err := NewStore(backend, secrets...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := newStore(t, NewMemoryBackend(), "secret")
+
+	saved, cookie := roundTrip(t, s, nil, func(session *gsessions.Session) {
+		session.Values["user"] = "alice"
+	})
+	if bytes.Contains([]byte(cookie.Value), []byte("alice")) {
+		t.Errorf("cookie %q carries the session data", cookie.Value)
+	}
+
+	session := load(t, s, cookie)
+	if session.IsNew || session.ID != saved.ID || session.Values["user"] != "alice" {
+		t.Errorf("loaded session %q (new %v) with %v, want %q with the saved values", session.ID, session.IsNew, session.Values, saved.ID)
+	}
+}
+
+func TestStoreDecodesCookiesOfOldSecrets(t *testing.T) {
+	backend := NewMemoryBackend()
+	_, cookie := roundTrip(t, newStore(t, backend, "old"), nil, func(session *gsessions.Session) {
+		session.Values["user"] = "alice"
+	})
+
+	if session := load(t, newStore(t, backend, "new", "old"), cookie); session.IsNew || session.Values["user"] != "alice" {
+		t.Errorf("after rotating secrets: new %v, values %v, want the session signed with the old secret", session.IsNew, session.Values)
+	}
+	if session := load(t, newStore(t, backend, "new"), cookie); !session.IsNew || session.ID != "" {
+		t.Errorf("after dropping the old secret: session %q (new %v), want a fresh one", session.ID, session.IsNew)
+	}
+}
+
+func TestStoreDropsUnknownAndForgedIDs(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := newStore(t, backend, "secret")
+	_, valid := roundTrip(t, s, nil, func(session *gsessions.Session) {
+		session.Values["user"] = "alice"
+	})
+
+	// Signed correctly, but the server never issued this ID
+	unknown, err := securecookie.EncodeMulti(cookieName, "AAAAAAAAAAAAAAAA", s.codecs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := []byte(valid.Value)
+	forged[len(forged)/2] ^= 1
+	for name, value := range map[string]string{
+		"unknown id":   unknown,
+		"forged":       string(forged),
+		"other secret": mustEncode(t, newStore(t, backend, "other"), "AAAAAAAAAAAAAAAA"),
+		"garbage":      "not a cookie",
+	} {
+		session, cookie := roundTrip(t, s, &http.Cookie{Name: cookieName, Value: value}, nil)
+		if session.ID == "AAAAAAAAAAAAAAAA" || !session.IsNew {
+			t.Errorf("%s: the store accepted session %q", name, session.ID)
+		}
+		if cookie.Value == value {
+			t.Errorf("%s: the store kept the cookie it was given", name)
+		}
+	}
+}
+
+func mustEncode(t *testing.T, s *Store, id string) string {
+	t.Helper()
+	value, err := securecookie.EncodeMulti(cookieName, id, s.codecs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestStoreRotate(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := newStore(t, backend, "secret")
+	before, cookie := roundTrip(t, s, nil, func(session *gsessions.Session) {
+		session.Values["step"] = "login"
+	})
+	oldID := before.ID
+
+	after, _ := roundTrip(t, s, cookie, func(session *gsessions.Session) {
+		session.Values["user"] = "alice"
+		// What Rotate sets through the gin session
+		session.Values[rotateKey] = true
+	})
+	if after.ID == oldID {
+		t.Fatal("Rotate kept the session id")
+	}
+	if _, ok := after.Values[rotateKey]; ok {
+		t.Error("the rotation mark was saved with the session")
+	}
+	if _, err := backend.Load(oldID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("data under the old id: %v, want ErrNotFound", err)
+	}
+	if session := load(t, s, cookie); !session.IsNew {
+		t.Error("the cookie issued before the rotation still opens the session")
+	}
+}
+
+func TestStoreDeletesSessionWithNegativeMaxAge(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := newStore(t, backend, "secret")
+	saved, cookie := roundTrip(t, s, nil, nil)
+
+	_, cleared := roundTrip(t, s, cookie, func(session *gsessions.Session) {
+		session.Options.MaxAge = -1
+	})
+	if cleared.Value != "" {
+		t.Errorf("cookie = %q, want it cleared", cleared.Value)
+	}
+	if _, err := backend.Load(saved.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load after logout: %v, want ErrNotFound", err)
+	}
+}
+
+func TestNewStoreRequiresSecrets(t *testing.T) {
+	if _, err := NewStore(NewMemoryBackend()); err == nil {
+		t.Error("NewStore without secrets succeeded")
+	}
+	if _, err := NewStore(NewMemoryBackend(), "secret", ""); err == nil {
+		t.Error("NewStore with an empty secret succeeded")
+	}
+}
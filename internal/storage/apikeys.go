@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// APIKeyStore хранит выданные пользователям API-ключи
+type APIKeyStore interface {
+	Save(key *models.APIKey) error
+	GetByHash(hash string) (*models.APIKey, error)
+	ListByUser(userID string) ([]models.APIKey, error)
+	Delete(userID, id string) error
+	MarkUsed(id string, at time.Time) error
+}
+
+// MemoryAPIKeyStore хранит API-ключи в памяти процесса
+type MemoryAPIKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[string]models.APIKey // по ID
+	byHash map[string]string        // ID ключа по хешу
+}
+
+// NewMemoryAPIKeyStore создает новый MemoryAPIKeyStore
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]models.APIKey), byHash: make(map[string]string)}
+}
+
+func (s *MemoryAPIKeyStore) Save(key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.keys[key.ID]; ok {
+		delete(s.byHash, old.Hash)
+	}
+	s.keys[key.ID] = *key
+	s.byHash[key.Hash] = key.ID
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) GetByHash(hash string) (*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[s.byHash[hash]]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (s *MemoryAPIKeyStore) ListByUser(userID string) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (s *MemoryAPIKeyStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.keys, id)
+	delete(s.byHash, key.Hash)
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) MarkUsed(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &at
+	s.keys[id] = key
+	return nil
+}
+
+// lastUsedWriteInterval — как часто FileAPIKeyStore сохраняет в файл время
+// последнего использования ключа: оно меняется на каждом запросе
+const lastUsedWriteInterval = time.Hour
+
+// apiKeyRecord — ключ в файле FileAPIKeyStore. Владелец и хеш не отдаются
+// в API, но в файле нужны.
+type apiKeyRecord struct {
+	UserID string `json:"user_id"`
+	Hash   string `json:"hash"`
+	models.APIKey
+}
+
+// FileAPIKeyStore держит ключи в памяти и после каждого изменения целиком
+// сохраняет их в JSON-файл, чтобы выданные ключи переживали перезапуск. В
+// файле только хеши ключей. Время последнего использования пишется не чаще
+// раза в lastUsedWriteInterval.
+type FileAPIKeyStore struct {
+	path     string
+	mu       sync.Mutex           // Порядок записи в файл
+	lastUsed map[string]time.Time // Последнее записанное в файл использование по ID
+	memory   *MemoryAPIKeyStore
+}
+
+// NewFileAPIKeyStore создает FileAPIKeyStore и читает сохраненные ключи
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create API keys directory: %w", err)
+	}
+	s := &FileAPIKeyStore{path: path, lastUsed: make(map[string]time.Time), memory: NewMemoryAPIKeyStore()}
+
+	var records []apiKeyRecord
+	if err := readJSONFile(path, &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		key := record.APIKey
+		key.UserID, key.Hash = record.UserID, record.Hash
+		_ = s.memory.Save(&key)
+		if key.LastUsedAt != nil {
+			s.lastUsed[key.ID] = *key.LastUsedAt
+		}
+	}
+	return s, nil
+}
+
+func (s *FileAPIKeyStore) Save(key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Save(key); err != nil {
+		return err
+	}
+	return s.write()
+}
+
+func (s *FileAPIKeyStore) GetByHash(hash string) (*models.APIKey, error) {
+	return s.memory.GetByHash(hash)
+}
+
+func (s *FileAPIKeyStore) ListByUser(userID string) ([]models.APIKey, error) {
+	return s.memory.ListByUser(userID)
+}
+
+func (s *FileAPIKeyStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Delete(userID, id); err != nil {
+		return err
+	}
+	delete(s.lastUsed, id)
+	return s.write()
+}
+
+func (s *FileAPIKeyStore) MarkUsed(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.MarkUsed(id, at); err != nil {
+		return err
+	}
+	if at.Sub(s.lastUsed[id]) < lastUsedWriteInterval {
+		return nil
+	}
+	s.lastUsed[id] = at
+	return s.write()
+}
+
+// write сохраняет все ключи в файл. Вызывается под s.mu.
+func (s *FileAPIKeyStore) write() error {
+	s.memory.mu.RLock()
+	records := make([]apiKeyRecord, 0, len(s.memory.keys))
+	for _, key := range s.memory.keys {
+		records = append(records, apiKeyRecord{UserID: key.UserID, Hash: key.Hash, APIKey: key})
+	}
+	s.memory.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.Before(records[j].CreatedAt) })
+	return writeJSONFile(s.path, records)
+}
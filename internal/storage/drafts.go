@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+const (
+	// DraftTTL — сколько живет черновик после последнего изменения
+	DraftTTL = 7 * 24 * time.Hour
+	// MaxDraftsPerUser — сколько черновиков хранится на пользователя; при
+	// превышении удаляется самый давно измененный
+	MaxDraftsPerUser = 50
+	// draftSweepInterval — как часто удаляются истекшие черновики
+	draftSweepInterval = time.Hour
+)
+
+// DraftStore хранит черновики сопроводительных писем пользователей
+type DraftStore interface {
+	Save(draft *models.CoverLetterDraft) error
+	Get(userID, id string) (*models.CoverLetterDraft, error)
+	Delete(userID, id string) error
+}
+
+// MemoryDraftStore хранит черновики в памяти процесса, не дольше DraftTTL
+// и не больше MaxDraftsPerUser на пользователя
+type MemoryDraftStore struct {
+	mu        sync.RWMutex
+	drafts    map[string]map[string]models.CoverLetterDraft // по пользователю и ID
+	lastSweep time.Time
+}
+
+// NewMemoryDraftStore создает новый MemoryDraftStore
+func NewMemoryDraftStore() *MemoryDraftStore {
+	return &MemoryDraftStore{drafts: make(map[string]map[string]models.CoverLetterDraft)}
+}
+
+func (s *MemoryDraftStore) Save(draft *models.CoverLetterDraft) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+
+	drafts, ok := s.drafts[draft.UserID]
+	if !ok {
+		drafts = make(map[string]models.CoverLetterDraft)
+		s.drafts[draft.UserID] = drafts
+	}
+	if _, ok := drafts[draft.ID]; !ok && len(drafts) >= MaxDraftsPerUser {
+		delete(drafts, oldestDraft(drafts))
+	}
+	drafts[draft.ID] = *draft
+	return nil
+}
+
+// Get возвращает черновик, только если он принадлежит userID
+func (s *MemoryDraftStore) Get(userID, id string) (*models.CoverLetterDraft, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	draft, ok := s.drafts[userID][id]
+	if !ok || draftExpired(draft, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &draft, nil
+}
+
+func (s *MemoryDraftStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	drafts := s.drafts[userID]
+	if _, ok := drafts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(drafts, id)
+	if len(drafts) == 0 {
+		delete(s.drafts, userID)
+	}
+	return nil
+}
+
+// sweep удаляет истекшие черновики не чаще раза в draftSweepInterval.
+// Вызывается под s.mu.
+func (s *MemoryDraftStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < draftSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for userID, drafts := range s.drafts {
+		for id, draft := range drafts {
+			if draftExpired(draft, now) {
+				delete(drafts, id)
+			}
+		}
+		if len(drafts) == 0 {
+			delete(s.drafts, userID)
+		}
+	}
+}
+
+func draftExpired(draft models.CoverLetterDraft, now time.Time) bool {
+	return now.Sub(draft.UpdatedAt) > DraftTTL
+}
+
+// oldestDraft возвращает ID черновика, который дольше всех не менялся
+func oldestDraft(drafts map[string]models.CoverLetterDraft) string {
+	var oldest string
+	for id, draft := range drafts {
+		if oldest == "" || draft.UpdatedAt.Before(drafts[oldest].UpdatedAt) {
+			oldest = id
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// SettingsStore хранит настройки пользователей
+type SettingsStore interface {
+	// Get возвращает настройки пользователя, а если их нет — пустые
+	Get(userID string) (*models.UserSettings, error)
+	Save(userID string, settings *models.UserSettings) error
+}
+
+// MemorySettingsStore хранит настройки в памяти процесса
+type MemorySettingsStore struct {
+	mu       sync.RWMutex
+	settings map[string]models.UserSettings // по ID пользователя
+}
+
+// NewMemorySettingsStore создает новый MemorySettingsStore
+func NewMemorySettingsStore() *MemorySettingsStore {
+	return &MemorySettingsStore{settings: make(map[string]models.UserSettings)}
+}
+
+func (s *MemorySettingsStore) Get(userID string) (*models.UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.settings[userID]
+	return &settings, nil
+}
+
+func (s *MemorySettingsStore) Save(userID string, settings *models.UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[userID] = *settings
+	return nil
+}
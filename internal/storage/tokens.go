@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// ErrNotFound возвращается, когда запись отсутствует в хранилище
+var ErrNotFound = errors.New("not found")
+
+// TokenStore хранит OAuth-токены hh.ru по идентификатору пользователя
+type TokenStore interface {
+	Get(userID string) (*models.HHToken, error)
+	Save(userID string, token *models.HHToken) error
+	Delete(userID string) error
+}
+
+// MemoryTokenStore хранит токены в памяти процесса
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]models.HHToken
+}
+
+// NewMemoryTokenStore создает новый MemoryTokenStore
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]models.HHToken)}
+}
+
+func (s *MemoryTokenStore) Get(userID string) (*models.HHToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &token, nil
+}
+
+func (s *MemoryTokenStore) Save(userID string, token *models.HHToken) error {
+	if token == nil {
+		return errors.New("token is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[userID] = *token
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, userID)
+	return nil
+}
+
+// FileTokenStore держит токены в памяти и после каждого изменения целиком
+// сохраняет их в JSON-файл, чтобы вход пользователей переживал перезапуск
+type FileTokenStore struct {
+	path   string
+	mu     sync.Mutex // Порядок записи в файл
+	memory *MemoryTokenStore
+}
+
+// NewFileTokenStore создает FileTokenStore и читает сохраненные токены
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create tokens directory: %w", err)
+	}
+	s := &FileTokenStore{path: path, memory: NewMemoryTokenStore()}
+	if err := readJSONFile(path, &s.memory.tokens); err != nil {
+		return nil, err
+	}
+	if s.memory.tokens == nil {
+		s.memory.tokens = make(map[string]models.HHToken)
+	}
+	return s, nil
+}
+
+func (s *FileTokenStore) Get(userID string) (*models.HHToken, error) {
+	return s.memory.Get(userID)
+}
+
+func (s *FileTokenStore) Save(userID string, token *models.HHToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Save(userID, token); err != nil {
+		return err
+	}
+	return s.write()
+}
+
+func (s *FileTokenStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Delete(userID); err != nil {
+		return err
+	}
+	return s.write()
+}
+
+// write сохраняет все токены в файл. Вызывается под s.mu.
+func (s *FileTokenStore) write() error {
+	s.memory.mu.RLock()
+	defer s.memory.mu.RUnlock()
+	return writeJSONFile(s.path, s.memory.tokens)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rustamnr/cover-letter-generator/internal/logger"
+	"github.com/rustamnr/cover-letter-generator/internal/models"
+)
+
+// UsageStore хранит расход токенов по генерациям
+type UsageStore interface {
+	Add(usage *models.GenerationUsage) error
+	// List возвращает генерации пользователя с from включительно до to в порядке создания
+	List(userID string, from, to time.Time) ([]models.GenerationUsage, error)
+	// Prune удаляет генерации, созданные раньше before
+	Prune(before time.Time) error
+}
+
+// MemoryUsageStore хранит расход в памяти процесса. Он теряется при
+// перезапуске вместе с потраченной частью бюджетов.
+type MemoryUsageStore struct {
+	mu    sync.RWMutex
+	usage map[string][]models.GenerationUsage // по ID пользователя
+}
+
+// NewMemoryUsageStore создает новый MemoryUsageStore
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{usage: make(map[string][]models.GenerationUsage)}
+}
+
+func (s *MemoryUsageStore) Add(usage *models.GenerationUsage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage[usage.UserID] = append(s.usage[usage.UserID], *usage)
+	return nil
+}
+
+func (s *MemoryUsageStore) List(userID string, from, to time.Time) ([]models.GenerationUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []models.GenerationUsage
+	for _, usage := range s.usage[userID] {
+		if !usage.CreatedAt.Before(from) && usage.CreatedAt.Before(to) {
+			list = append(list, usage)
+		}
+	}
+	return list, nil
+}
+
+func (s *MemoryUsageStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, list := range s.usage {
+		kept := list[:0]
+		for _, usage := range list {
+			if !usage.CreatedAt.Before(before) {
+				kept = append(kept, usage)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.usage, userID)
+		} else {
+			s.usage[userID] = kept
+		}
+	}
+	return nil
+}
+
+// all возвращает все генерации в порядке пользователей и создания
+func (s *MemoryUsageStore) all() []models.GenerationUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var all []models.GenerationUsage
+	for _, list := range s.usage {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// usageLine — строка файла FileUsageStore. ID пользователя не отдается в API,
+// но в файле нужен.
+type usageLine struct {
+	UserID string `json:"user_id"`
+	models.GenerationUsage
+}
+
+// FileUsageStore держит расход в памяти и дописывает каждую генерацию строкой
+// JSON в файл, чтобы бюджеты и история переживали перезапуск. Prune
+// переписывает файл без удаленных записей.
+type FileUsageStore struct {
+	path   string
+	mu     sync.Mutex // Порядок записи в файл
+	memory *MemoryUsageStore
+}
+
+// NewFileUsageStore создает FileUsageStore и читает сохраненный расход.
+// Недописанные строки, например после аварийной остановки, пропускаются.
+func NewFileUsageStore(path string) (*FileUsageStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	s := &FileUsageStore{path: path, memory: NewMemoryUsageStore()}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line usageLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.UserID == "" {
+			logger.Warnf("skipping a corrupted line of %s", path)
+			continue
+		}
+		line.GenerationUsage.UserID = line.UserID
+		_ = s.memory.Add(&line.GenerationUsage)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileUsageStore) Add(usage *models.GenerationUsage) error {
+	data, err := json.Marshal(usageLine{UserID: usage.UserID, GenerationUsage: *usage})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return s.memory.Add(usage)
+}
+
+func (s *FileUsageStore) List(userID string, from, to time.Time) ([]models.GenerationUsage, error) {
+	return s.memory.List(userID, from, to)
+}
+
+func (s *FileUsageStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.memory.Prune(before); err != nil {
+		return err
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не потерять расход при сбое
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tmp_usage")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, usage := range s.memory.all() {
+		data, err := json.Marshal(usageLine{UserID: usage.UserID, GenerationUsage: usage})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -1,6 +1,6 @@
 package promts
 
-const DeepseekSystemContext = "Ты профессиональный генератор сопроводительных писем. Твоя задача - создать персонализированное, " +
+const CoverLetterSystemContext = "Ты профессиональный генератор сопроводительных писем. Твоя задача - создать персонализированное, " +
 	"убедительное сопроводительное письмо на основе резюме кандидата и описания вакансии.\n\n" +
 	"Сопроводительное письмо должно:\n" +
 	"Иметь профессиональный, но живой тон\n" +